@@ -74,6 +74,67 @@ var (
 	DefaultActions = []Condition{Eq, Ne, Gt, Gte, Lt, Lte, In, NotIn, Like, NotLike, LikeRight, LikeLeft, FK, IsNull, IsNotNull, Sort}
 )
 
+// 方言名称，与 gorm Dialector.Name() 一致
+const (
+	DialectMySQL    = "mysql"
+	DialectPostgres = "postgres"
+	DialectSQLite   = "sqlite"
+)
+
+// Dialect 返回 db 使用的方言名称
+func Dialect(db *gorm.DB) string {
+	if db == nil || db.Dialector == nil {
+		return ""
+	}
+	return db.Dialector.Name()
+}
+
+// globEscaper 转义 GLOB 的通配符，使其按字面匹配
+var globEscaper = strings.NewReplacer("*", "[*]", "?", "[?]", "[", "[[]")
+
+// likeWhere 以 Postgres 的语义为准，LIKE 区分大小写：SQLite 的 LIKE 不区分大小写，
+// 改用 GLOB 并将 % / _ 转换为 * / ?；MySQL 由列的排序规则决定（*_bin 排序规则区分大小写）
+func likeWhere(db *gorm.DB, k, pattern string, not bool) *gorm.DB {
+	op := " LIKE ?"
+	if Dialect(db) == DialectSQLite {
+		op = " GLOB ?"
+		var b strings.Builder
+		for _, r := range pattern {
+			switch r {
+			case '%':
+				b.WriteByte('*')
+			case '_':
+				b.WriteByte('?')
+			default:
+				b.WriteString(globEscaper.Replace(string(r)))
+			}
+		}
+		pattern = b.String()
+	}
+	if not {
+		op = " NOT" + op
+	}
+	return db.Where(k+op, pattern)
+}
+
+// inValues IN 的参数必须为切片，单个值包装为单元素切片
+func inValues(v interface{}) interface{} {
+	switch v.(type) {
+	case []interface{}, []string, []int, []int64, []uint, []uint64, []float64:
+		return v
+	default:
+		return []interface{}{v}
+	}
+}
+
+// likeValue 将参数转为字符串
+func likeValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
 var (
 	EqAct = func(db *gorm.DB, k string, v interface{}) *gorm.DB {
 		return db.Where(k+" = ?", v)
@@ -94,22 +155,22 @@ var (
 		return db.Where(k+" <= ?", v)
 	}
 	InAct = func(db *gorm.DB, k string, v interface{}) *gorm.DB {
-		return db.Where(k+" IN ?", v)
+		return db.Where(k+" IN ?", inValues(v))
 	}
 	NotInAct = func(db *gorm.DB, k string, v interface{}) *gorm.DB {
-		return db.Where(k+" NOT IN ?", v)
+		return db.Where(k+" NOT IN ?", inValues(v))
 	}
 	LikeAct = func(db *gorm.DB, k string, v interface{}) *gorm.DB {
-		return db.Where(k+" LIKE ?", "%"+v.(string)+"%")
+		return likeWhere(db, k, "%"+likeValue(v)+"%", false)
 	}
 	NotLikeAct = func(db *gorm.DB, k string, v interface{}) *gorm.DB {
-		return db.Where(k+" NOT LIKE ?", "%"+v.(string)+"%")
+		return likeWhere(db, k, "%"+likeValue(v)+"%", true)
 	}
 	LikeRightAct = func(db *gorm.DB, k string, v interface{}) *gorm.DB {
-		return db.Where(k+" LIKE ?", v.(string)+"%")
+		return likeWhere(db, k, likeValue(v)+"%", false)
 	}
 	LikeLeftAct = func(db *gorm.DB, k string, v interface{}) *gorm.DB {
-		return db.Where(k+" LIKE ?", "%"+v.(string))
+		return likeWhere(db, k, "%"+likeValue(v), false)
 	}
 	/**
 	 * @param fk 关联表外键 author_id
@@ -128,7 +189,16 @@ var (
 	IsNotNullAct = func(db *gorm.DB, k string, v interface{}) *gorm.DB {
 		return db.Where(k + " IS NOT NULL")
 	}
+	// SortAct 在 SQLite 上与 Postgres 一致，NULL 视为最大值（升序在后、降序在前）；
+	// MySQL 保持默认（NULL 视为最小值），以便使用索引排序
 	SortAct = func(db *gorm.DB, k string, v interface{}) *gorm.DB {
-		return db.Order(k + " " + v.(string))
+		dir := strings.ToLower(v.(string))
+		if Dialect(db) == DialectSQLite {
+			if dir == "desc" {
+				return db.Order(k + " desc NULLS FIRST")
+			}
+			return db.Order(k + " " + dir + " NULLS LAST")
+		}
+		return db.Order(k + " " + dir)
 	}
 )
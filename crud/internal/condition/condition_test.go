@@ -3,7 +3,10 @@ package condition_test
 import (
 	"testing"
 
+	"github.com/glebarez/sqlite"
 	"github.com/lazyfury/bowlutils/crud/internal/condition"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
@@ -276,3 +279,49 @@ func TestComparisonActions(t *testing.T) {
 		})
 	}
 }
+
+// SQLite 的 LIKE 与 NULL 排序按 Postgres 的语义转换，MySQL 保持默认
+func TestActions_Dialects(t *testing.T) {
+	config := func() *gorm.Config { return &gorm.Config{DryRun: true, DisableAutomaticPing: true} }
+	postgresDB, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost dbname=test"}), config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mysqlDB, err := gorm.Open(mysql.New(mysql.Config{DSN: "root@tcp(localhost)/test", SkipInitializeWithVersion: true}), config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqliteDB, err := gorm.Open(sqlite.Open(":memory:"), config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		db       *gorm.DB
+		fn       func(*gorm.DB) *gorm.DB
+		want     string
+		wantVars []interface{}
+	}{
+		{postgresDB, func(db *gorm.DB) *gorm.DB { return condition.LikeAct(db, "name", "a") }, `SELECT * FROM "users" WHERE name LIKE $1`, []interface{}{"%a%"}},
+		{postgresDB, func(db *gorm.DB) *gorm.DB { return condition.NotLikeAct(db, "name", "a") }, `SELECT * FROM "users" WHERE name NOT LIKE $1`, []interface{}{"%a%"}},
+		{postgresDB, func(db *gorm.DB) *gorm.DB { return condition.SortAct(db, "age", "desc") }, `SELECT * FROM "users" ORDER BY age desc`, nil},
+		{mysqlDB, func(db *gorm.DB) *gorm.DB { return condition.LikeRightAct(db, "name", "a") }, "SELECT * FROM `users` WHERE name LIKE ?", []interface{}{"a%"}},
+		{mysqlDB, func(db *gorm.DB) *gorm.DB { return condition.SortAct(db, "age", "asc") }, "SELECT * FROM `users` ORDER BY age asc", nil},
+		{mysqlDB, func(db *gorm.DB) *gorm.DB { return condition.SortAct(db, "age", "desc") }, "SELECT * FROM `users` ORDER BY age desc", nil},
+		{sqliteDB, func(db *gorm.DB) *gorm.DB { return condition.LikeAct(db, "name", "a*_b") }, "SELECT * FROM `users` WHERE name GLOB ?", []interface{}{"*a[*]?b*"}},
+		{sqliteDB, func(db *gorm.DB) *gorm.DB { return condition.NotLikeAct(db, "name", "[x]?") }, "SELECT * FROM `users` WHERE name NOT GLOB ?", []interface{}{"*[[]x][?]*"}},
+		{sqliteDB, func(db *gorm.DB) *gorm.DB { return condition.LikeLeftAct(db, "name", 5) }, "SELECT * FROM `users` WHERE name GLOB ?", []interface{}{"*5"}},
+		{sqliteDB, func(db *gorm.DB) *gorm.DB { return condition.SortAct(db, "age", "asc") }, "SELECT * FROM `users` ORDER BY age asc NULLS LAST", nil},
+		{sqliteDB, func(db *gorm.DB) *gorm.DB { return condition.SortAct(db, "age", "desc") }, "SELECT * FROM `users` ORDER BY age desc NULLS FIRST", nil},
+	}
+	for _, tt := range tests {
+		var out []map[string]interface{}
+		stmt := tt.fn(tt.db.Table("users")).Find(&out).Statement
+		if got := stmt.SQL.String(); got != tt.want {
+			t.Errorf("%s: SQL = %s, want %s", tt.db.Dialector.Name(), got, tt.want)
+		}
+		if len(stmt.Vars) != len(tt.wantVars) || (len(tt.wantVars) > 0 && stmt.Vars[0] != tt.wantVars[0]) {
+			t.Errorf("%s: vars = %v, want %v", tt.db.Dialector.Name(), stmt.Vars, tt.wantVars)
+		}
+	}
+}
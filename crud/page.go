@@ -1,9 +1,59 @@
 package crud
 
+import "gorm.io/gorm"
+
 type Page[T any] struct {
-	PageNum   int64 `json:"page_num"`
-	PageSize  int64 `json:"page_size"`
-	PageCount int64 `json:"page_count"`
-	Total     int64 `json:"total"`
-	Items     *[]T  `json:"items"`
+	PageNum    int64 `json:"page_num"`
+	PageSize   int64 `json:"page_size"`
+	PageCount  int64 `json:"page_count"`
+	Total      int64 `json:"total"`
+	TotalExact bool  `json:"total_exact"` // false 表示 total 为上限或估算值，或未统计
+	HasNext    bool  `json:"has_next"`
+	Items      *[]T  `json:"items"`
+}
+
+// TotalMode Page 统计总数的方式
+type TotalMode int
+
+const (
+	TotalModeExact    TotalMode = iota // COUNT(*) 精确统计
+	TotalModeNone                      // 不统计，多取一条判断 has_next
+	TotalModeCapped                    // 最多统计到 N 条
+	TotalModeEstimate                  // 无过滤条件时使用数据库统计信息估算
+)
+
+const settingPageTotal = "crud:page_total"
+
+type totalOption struct {
+	mode TotalMode
+	cap  int64
+}
+
+func withTotalOption(opt totalOption) QueryFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Set(settingPageTotal, opt)
+	}
+}
+
+func pageTotalOption(db *gorm.DB) totalOption {
+	if v, ok := db.Get(settingPageTotal); ok {
+		return v.(totalOption)
+	}
+	return totalOption{mode: TotalModeExact}
+}
+
+// WithoutTotal Page 不统计总数，只返回 has_next
+func WithoutTotal() QueryFunc {
+	return withTotalOption(totalOption{mode: TotalModeNone})
+}
+
+// WithTotalCap Page 最多统计到 n 条，超过时 total = n 且 total_exact = false
+func WithTotalCap(n int64) QueryFunc {
+	return withTotalOption(totalOption{mode: TotalModeCapped, cap: n})
+}
+
+// WithEstimatedTotal Page 在没有过滤条件时使用 pg_class.reltuples / information_schema 估算总数；
+// 估算值包括软删除的记录，因此软删除模型（未 Unscoped 时）仍精确统计
+func WithEstimatedTotal() QueryFunc {
+	return withTotalOption(totalOption{mode: TotalModeEstimate})
 }
@@ -0,0 +1,192 @@
+package crud
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/lazyfury/bowlutils/utils"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrPatchField 字段不存在或不在允许列表中
+	ErrPatchField = errors.New("crud: field is not patchable")
+	// ErrPatchBody merge patch 请求体不是 JSON 对象
+	ErrPatchBody = errors.New("crud: merge patch body must be a JSON object")
+)
+
+// Patch 部分更新，changes 的 key 为 json 字段名，支持 nil（写入 NULL）与零值
+// allowed 为允许修改的字段，为空时允许除 id 外的所有模型字段
+// 返回更新后的实体与实际发生变化的字段（按字母排序）；Policy 对已存储的记录与更新后的记录各检查一次
+func (r *Repository[T]) Patch(id uint, changes map[string]interface{}, allowed []string) (T, []string, error) {
+	var zero T
+	if err := r.checkPatchKeys(changes, allowed); err != nil {
+		return zero, nil, err
+	}
+	opts, err := r.scoped(nil)
+	if err != nil {
+		return zero, nil, err
+	}
+	return r.patch(id, changes, opts)
+}
+
+// patch 在 opts 读取范围内加载记录并更新 changes，不检查 key
+func (r *Repository[T]) patch(id uint, changes map[string]interface{}, opts []QueryFunc) (T, []string, error) {
+	var result T
+	var changed []string
+	err := r.conn().Transaction(func(tx *gorm.DB) error {
+		var existing T
+		db := tx.Table(r.model.TableName()).Where("id = ?", id)
+		for _, opt := range opts {
+			db = opt(db)
+		}
+		if err := db.First(&existing).Error; err != nil {
+			return err
+		}
+		if err := r.authorize(ActionUpdate, existing); err != nil {
+			return err
+		}
+		current, err := utils.ToMap(existing)
+		if err != nil {
+			return err
+		}
+
+		updates := make(map[string]interface{}, len(changes))
+		for k, v := range changes {
+			if !patchValueEqual(current[k], v) {
+				updates[k] = v
+				changed = append(changed, k)
+			}
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&existing).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Table(r.model.TableName()).Where("id = ?", id).First(&result).Error; err != nil {
+			return err
+		}
+		// 更新后的记录也要允许 update，避免修改 owner 等字段把记录移出或移入他人的范围
+		if len(updates) > 0 {
+			return r.authorize(ActionUpdate, result)
+		}
+		return nil
+	})
+	if err != nil {
+		var zero T
+		return zero, nil, err
+	}
+	sort.Strings(changed)
+	return result, changed, nil
+}
+
+// PatchJSON 以 JSON Merge Patch（RFC 7396）请求体部分更新
+// 值为 null 的字段写入 NULL，值为对象的字段与当前值递归合并后以 JSON 字符串写入
+func (r *Repository[T]) PatchJSON(id uint, body []byte, allowed []string) (T, []string, error) {
+	var zero T
+	var patch interface{}
+	if err := json.Unmarshal(body, &patch); err != nil {
+		return zero, nil, err
+	}
+	obj, ok := patch.(map[string]interface{})
+	if !ok {
+		return zero, nil, ErrPatchBody
+	}
+	if err := r.checkPatchKeys(obj, allowed); err != nil {
+		return zero, nil, err
+	}
+
+	var nested []string
+	for k, v := range obj {
+		if _, ok := v.(map[string]interface{}); ok {
+			nested = append(nested, k)
+		}
+	}
+	if len(nested) > 0 {
+		existing, err := r.FindByID(id)
+		if err != nil {
+			return zero, nil, err
+		}
+		current, err := utils.ToMap(existing)
+		if err != nil {
+			return zero, nil, err
+		}
+		for _, k := range nested {
+			target := current[k]
+			// JSON 列可能以字符串形式编码
+			if s, ok := target.(string); ok {
+				var decoded interface{}
+				if json.Unmarshal([]byte(s), &decoded) == nil {
+					target = decoded
+				}
+			}
+			merged, err := json.Marshal(MergePatch(target, obj[k]))
+			if err != nil {
+				return zero, nil, err
+			}
+			obj[k] = string(merged)
+		}
+	}
+	return r.Patch(id, obj, allowed)
+}
+
+func (r *Repository[T]) checkPatchKeys(changes map[string]interface{}, allowed []string) error {
+	keys := r.ReflectKeys()
+	for k := range changes {
+		if k == "id" || !containsString(keys, k) {
+			return fmt.Errorf("%w: %s", ErrPatchField, k)
+		}
+		if len(allowed) > 0 && !containsString(allowed, k) {
+			return fmt.Errorf("%w: %s", ErrPatchField, k)
+		}
+	}
+	return nil
+}
+
+// MergePatch 按 RFC 7396 将 patch 合并到 target，返回新值（不修改 target）
+func MergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	result := make(map[string]interface{}, len(patchObj))
+	if ok {
+		for k, v := range targetObj {
+			result[k] = v
+		}
+	}
+	for k, v := range patchObj {
+		if v == nil {
+			delete(result, k)
+			continue
+		}
+		result[k] = MergePatch(result[k], v)
+	}
+	return result
+}
+
+// patchValueEqual 以 JSON 编码结果比较当前值与新值
+func patchValueEqual(current, next interface{}) bool {
+	data, err := json.Marshal(next)
+	if err != nil {
+		return false
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(current, normalized)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
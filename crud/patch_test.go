@@ -0,0 +1,129 @@
+package crud
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	bowldb "github.com/lazyfury/bowlutils/db"
+	"gorm.io/gorm"
+)
+
+type patchUser struct {
+	BaseModel
+	Name   string  `json:"name"`
+	Age    int     `json:"age"`
+	Active bool    `json:"active"`
+	Note   *string `json:"note"`
+}
+
+func (u *patchUser) TableName() string { return "users" }
+
+func newPatchDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	conn, err := bowldb.Open(bowldb.DBConfig{Driver: bowldb.DriverSQLite, DSN: bowldb.SQLiteMemory, LogLevel: "silent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.AutoMigrate(&patchUser{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { bowldb.Close(conn) })
+	return conn
+}
+
+func TestRepository_Patch(t *testing.T) {
+	repo := NewRepository(&patchUser{}, newPatchDB(t))
+	note := "hello"
+	u := &patchUser{Name: "alice", Age: 30, Active: true, Note: &note}
+	if err := repo.Create(u); err != nil {
+		t.Fatal(err)
+	}
+
+	got, changed, err := repo.Patch(u.ID, map[string]interface{}{"age": 0, "active": false, "note": nil, "name": "alice"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Age != 0 || got.Active || got.Note != nil {
+		t.Fatalf("Patch result = %+v", got)
+	}
+	if len(changed) != 3 || changed[0] != "active" || changed[1] != "age" || changed[2] != "note" {
+		t.Fatalf("changed = %v", changed)
+	}
+
+	if _, _, err := repo.Patch(u.ID, map[string]interface{}{"age": 1}, []string{"name"}); !errors.Is(err, ErrPatchField) {
+		t.Fatalf("expected ErrPatchField, got %v", err)
+	}
+	if _, _, err := repo.Patch(u.ID, map[string]interface{}{"password": "x"}, nil); !errors.Is(err, ErrPatchField) {
+		t.Fatalf("expected ErrPatchField, got %v", err)
+	}
+
+	got, changed, err = repo.PatchJSON(u.ID, []byte(`{"name":"bob","note":"n"}`), []string{"name", "note"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "bob" || got.Note == nil || *got.Note != "n" || len(changed) != 2 {
+		t.Fatalf("PatchJSON = %+v %v", got, changed)
+	}
+}
+
+// RFC 7396 Appendix A
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		target string
+		patch  string
+		want   string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.patch, func(t *testing.T) {
+			var target, patch, want interface{}
+			mustUnmarshal(t, tt.target, &target)
+			mustUnmarshal(t, tt.patch, &patch)
+			mustUnmarshal(t, tt.want, &want)
+
+			got, _ := json.Marshal(MergePatch(target, patch))
+			wantJSON, _ := json.Marshal(want)
+			if string(got) != string(wantJSON) {
+				t.Errorf("MergePatch(%s, %s) = %s, want %s", tt.target, tt.patch, got, wantJSON)
+			}
+		})
+	}
+}
+
+func TestPatchValueEqual(t *testing.T) {
+	if !patchValueEqual(float64(3), 3) {
+		t.Error("3 should equal float64(3)")
+	}
+	if !patchValueEqual(nil, nil) {
+		t.Error("nil should equal nil")
+	}
+	if patchValueEqual(true, false) {
+		t.Error("true should not equal false")
+	}
+	if patchValueEqual("", nil) {
+		t.Error("empty string should not equal nil")
+	}
+}
+
+func mustUnmarshal(t *testing.T, s string, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal([]byte(s), v); err != nil {
+		t.Fatal(err)
+	}
+}
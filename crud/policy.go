@@ -0,0 +1,182 @@
+package crud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+/*
+Policy 使用示例:
+
+	type orderPolicy struct{}
+
+	// 普通用户只能看到自己的订单
+	func (orderPolicy) Scope(ctx context.Context) (crud.QueryFunc, error) {
+		user, ok := crud.ActorFrom[*User](ctx)
+		if !ok {
+			return nil, crud.ErrNoActor
+		}
+		if user.IsAdmin {
+			return nil, nil
+		}
+		return func(db *gorm.DB) *gorm.DB { return db.Where("user_id = ?", user.ID) }, nil
+	}
+
+	func (orderPolicy) Allow(ctx context.Context, action crud.Action, order *Order) (bool, error) {
+		user, ok := crud.ActorFrom[*User](ctx)
+		return ok && (user.IsAdmin || order.UserID == user.ID), nil
+	}
+
+	repo := crud.NewRepository(&Order{}, db).WithPolicy(orderPolicy{})
+
+	// handler
+	ctx := crud.WithActor(r.Context(), currentUser)
+	orders, err := repo.WithContext(ctx).FindByID(id)
+	if crud.IsForbidden(err) {
+		resp.Forbidden[any](w, err.Error())
+		return
+	}
+*/
+
+// Action 写操作类型
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+var (
+	// ErrNoActor context 中没有 actor
+	ErrNoActor = &ForbiddenError{Reason: "no actor in context"}
+)
+
+// Policy 行级权限策略，actor 通过 WithActor 放入 context；
+// Update、Query、DB 直接操作数据库，不经过 Policy
+type Policy[T Model] interface {
+	// Scope 返回附加到读操作（List/Page/FindByID/Exists/Chunk 以及 TreeRepository 的节点查找）上的查询条件，
+	// 返回 nil 表示不限制
+	Scope(ctx context.Context) (QueryFunc, error)
+	// Allow 判断是否允许对 model 执行写操作（Create/Updates/Save/Patch/DeleteByID，
+	// TreeRepository.CreateNode 为 create，Move 为 update）；
+	// Updates/Save/Patch 对已存储的记录与更新后的 model 各检查一次，Move 检查移动的子树与新的父节点
+	Allow(ctx context.Context, action Action, model T) (bool, error)
+}
+
+// ForbiddenError 权限拒绝错误，对应 HTTP 403（resp.Forbidden）
+type ForbiddenError struct {
+	Table  string
+	Action Action
+	Reason string
+}
+
+func (e *ForbiddenError) Error() string {
+	if e.Reason != "" {
+		return "forbidden: " + e.Reason
+	}
+	return fmt.Sprintf("forbidden: %s on %s", e.Action, e.Table)
+}
+
+// StatusCode 对应的 HTTP 状态码
+func (e *ForbiddenError) StatusCode() int {
+	return http.StatusForbidden
+}
+
+// IsForbidden 判断错误是否为权限拒绝
+func IsForbidden(err error) bool {
+	var fe *ForbiddenError
+	return errors.As(err, &fe)
+}
+
+type actorKey struct{}
+
+// WithActor 将当前操作者放入 context
+func WithActor(ctx context.Context, actor any) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom 从 context 中取出指定类型的操作者
+func ActorFrom[A any](ctx context.Context) (A, bool) {
+	actor, ok := ctx.Value(actorKey{}).(A)
+	return actor, ok
+}
+
+// WithPolicy 返回使用 policy 的仓储副本
+func (r *Repository[T]) WithPolicy(policy Policy[T]) *Repository[T] {
+	c := *r
+	c.policy = policy
+	return &c
+}
+
+// WithContext 返回绑定 ctx 的仓储副本，ctx 会传递给 gorm 与 Policy
+func (r *Repository[T]) WithContext(ctx context.Context) *Repository[T] {
+	c := *r
+	c.db = r.db.WithContext(ctx)
+	return &c
+}
+
+// WithScope 返回附加读取条件的仓储副本，与 policy 的 Scope 一样作用于读操作
+func (r *Repository[T]) WithScope(scopes ...QueryFunc) *Repository[T] {
+	c := *r
+	c.scopes = append(append([]QueryFunc(nil), r.scopes...), scopes...)
+	return &c
+}
+
+func (r *Repository[T]) context() context.Context {
+	if r.db.Statement != nil && r.db.Statement.Context != nil {
+		return r.db.Statement.Context
+	}
+	return context.Background()
+}
+
+// scoped 在 opts 前加上 WithScope 与 policy 的读取条件
+func (r *Repository[T]) scoped(opts []QueryFunc) ([]QueryFunc, error) {
+	scopes := r.scopes
+	if r.policy != nil {
+		scope, err := r.policy.Scope(r.context())
+		if err != nil {
+			return nil, err
+		}
+		if scope != nil {
+			scopes = append(scopes[:len(scopes):len(scopes)], scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return opts, nil
+	}
+	return append(append([]QueryFunc(nil), scopes...), opts...), nil
+}
+
+// authorize 检查写操作权限
+func (r *Repository[T]) authorize(action Action, model T) error {
+	if r.policy == nil {
+		return nil
+	}
+	ok, err := r.policy.Allow(r.context(), action, model)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &ForbiddenError{Table: r.model.TableName(), Action: action}
+	}
+	return nil
+}
+
+// authorizeUpdate 在读取范围内加载已存储的记录，检查其与更新后的 model 是否都允许 update，
+// 避免调用方通过修改 owner 等字段绕过检查
+func (r *Repository[T]) authorizeUpdate(model T) error {
+	if r.policy == nil {
+		return r.AssetExists(model.GetID())
+	}
+	stored, err := r.FindByID(model.GetID())
+	if err != nil {
+		return err
+	}
+	if err := r.authorize(ActionUpdate, stored); err != nil {
+		return err
+	}
+	return r.authorize(ActionUpdate, model)
+}
@@ -0,0 +1,319 @@
+package crud_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lazyfury/bowlutils/crud"
+	"gorm.io/gorm"
+)
+
+type document struct {
+	crud.BaseModel
+	OwnerID uint   `json:"owner_id"`
+	Title   string `json:"title"`
+}
+
+func (d *document) TableName() string { return "documents" }
+
+type actor struct {
+	ID    uint
+	Admin bool
+}
+
+// ownerPolicy 非管理员只能读写自己的文档
+type ownerPolicy struct{}
+
+func (ownerPolicy) Scope(ctx context.Context) (crud.QueryFunc, error) {
+	a, ok := crud.ActorFrom[*actor](ctx)
+	if !ok {
+		return nil, crud.ErrNoActor
+	}
+	if a.Admin {
+		return nil, nil
+	}
+	return func(db *gorm.DB) *gorm.DB { return db.Where("owner_id = ?", a.ID) }, nil
+}
+
+func (ownerPolicy) Allow(ctx context.Context, action crud.Action, d *document) (bool, error) {
+	a, ok := crud.ActorFrom[*actor](ctx)
+	if !ok {
+		return false, crud.ErrNoActor
+	}
+	if action == crud.ActionDelete && d.Title == "locked" {
+		return a.Admin, nil
+	}
+	return a.Admin || d.OwnerID == a.ID, nil
+}
+
+// sharedPolicy 所有人都能读取，只有所有者可以写
+type sharedPolicy struct{ ownerPolicy }
+
+func (sharedPolicy) Scope(ctx context.Context) (crud.QueryFunc, error) { return nil, nil }
+
+func TestPolicy_Read(t *testing.T) {
+	base := crud.NewRepository(&document{}, newTestDB(t, &document{}))
+	var docs []*document
+	for i, owner := range []uint{1, 1, 2} {
+		d := &document{OwnerID: owner, Title: string(rune('a' + i))}
+		if err := base.Create(d); err != nil {
+			t.Fatal(err)
+		}
+		docs = append(docs, d)
+	}
+
+	policy := base.WithPolicy(ownerPolicy{})
+	repo := policy.WithContext(crud.WithActor(context.Background(), &actor{ID: 1}))
+	admin := policy.WithContext(crud.WithActor(context.Background(), &actor{ID: 9, Admin: true}))
+
+	var out []*document
+	if err := repo.List(&out); err != nil || len(out) != 2 {
+		t.Fatalf("List = %d, %v", len(out), err)
+	}
+	if err := admin.List(&out); err != nil || len(out) != 3 {
+		t.Fatalf("admin List = %d, %v", len(out), err)
+	}
+
+	page, err := repo.Page(&out, 1, 10)
+	if err != nil || page.Total != 2 || len(*page.Items) != 2 {
+		t.Fatalf("Page = %+v, %v", page, err)
+	}
+
+	if _, err := repo.FindByID(docs[2].ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("FindByID other owner err = %v", err)
+	}
+	if got, err := repo.FindByID(docs[0].ID); err != nil || got.ID != docs[0].ID {
+		t.Fatalf("FindByID = %+v, %v", got, err)
+	}
+
+	if ok, err := repo.Exists(docs[2].ID); err != nil || ok {
+		t.Fatalf("Exists other owner = %v, %v", ok, err)
+	}
+
+	var seen int
+	if err := repo.Chunk(1, func(items []*document) error {
+		for _, d := range items {
+			if d.OwnerID != 1 {
+				t.Fatalf("Chunk leaked %+v", d)
+			}
+		}
+		seen += len(items)
+		return nil
+	}); err != nil || seen != 2 {
+		t.Fatalf("Chunk seen = %d, %v", seen, err)
+	}
+
+	// 没有 actor
+	noActor := policy.WithContext(context.Background())
+	if err := noActor.List(&out); !errors.Is(err, crud.ErrNoActor) || !crud.IsForbidden(err) {
+		t.Fatalf("List without actor err = %v", err)
+	}
+	if _, err := noActor.FindByID(docs[0].ID); !errors.Is(err, crud.ErrNoActor) {
+		t.Fatalf("FindByID without actor err = %v", err)
+	}
+}
+
+func TestPolicy_Write(t *testing.T) {
+	base := crud.NewRepository(&document{}, newTestDB(t, &document{}))
+	mine := &document{OwnerID: 1, Title: "mine"}
+	locked := &document{OwnerID: 1, Title: "locked"}
+	theirs := &document{OwnerID: 2, Title: "theirs"}
+	for _, d := range []*document{mine, locked, theirs} {
+		if err := base.Create(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo := base.WithPolicy(ownerPolicy{}).WithContext(crud.WithActor(context.Background(), &actor{ID: 1}))
+
+	if err := repo.Create(&document{OwnerID: 2, Title: "forged"}); !crud.IsForbidden(err) {
+		t.Fatalf("Create err = %v", err)
+	}
+	if err := repo.Create(&document{OwnerID: 1, Title: "new"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 转移给其他人被拒绝
+	mine.OwnerID = 2
+	if err := repo.Updates(mine); !crud.IsForbidden(err) {
+		t.Fatalf("Updates err = %v", err)
+	}
+	if err := repo.Save(mine); !crud.IsForbidden(err) {
+		t.Fatalf("Save err = %v", err)
+	}
+	mine.OwnerID, mine.Title = 1, "changed"
+	if err := repo.Updates(mine); err != nil {
+		t.Fatal(err)
+	}
+	// 其他人的文档在读取范围外，表现为不存在
+	theirs.Title = "changed"
+	if err := repo.Updates(theirs); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("Updates other owner err = %v", err)
+	}
+
+	if err := repo.DeleteByID(locked.ID); !crud.IsForbidden(err) {
+		t.Fatalf("DeleteByID locked err = %v", err)
+	}
+	if err := repo.DeleteByID(theirs.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("DeleteByID other owner err = %v", err)
+	}
+	for _, d := range []*document{locked, theirs} {
+		if _, err := base.FindByID(d.ID); err != nil {
+			t.Fatalf("%s deleted: %v", d.Title, err)
+		}
+	}
+	if err := repo.DeleteByID(mine.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	var fe *crud.ForbiddenError
+	err := repo.Create(&document{OwnerID: 3})
+	if !errors.As(err, &fe) || fe.Action != crud.ActionCreate || fe.Table != "documents" || fe.StatusCode() != 403 {
+		t.Fatalf("ForbiddenError = %+v", fe)
+	}
+
+	if err := base.WithPolicy(ownerPolicy{}).Create(&document{OwnerID: 1}); !errors.Is(err, crud.ErrNoActor) {
+		t.Fatalf("Create without actor err = %v", err)
+	}
+
+	// 检查已存储的记录：把 owner 改成自己不能接管他人的文档
+	shared := base.WithPolicy(sharedPolicy{}).WithContext(crud.WithActor(context.Background(), &actor{ID: 1}))
+	forged := &document{OwnerID: 1, Title: "taken"}
+	forged.ID = theirs.ID
+	if err := shared.Updates(forged); !crud.IsForbidden(err) {
+		t.Fatalf("Updates forged owner err = %v", err)
+	}
+	if err := shared.Save(forged); !crud.IsForbidden(err) {
+		t.Fatalf("Save forged owner err = %v", err)
+	}
+	if got, err := base.FindByID(theirs.ID); err != nil || got.OwnerID != 2 || got.Title != "theirs" {
+		t.Fatalf("theirs = %+v, %v", got, err)
+	}
+}
+
+func TestPolicy_Patch(t *testing.T) {
+	base := crud.NewRepository(&document{}, newTestDB(t, &document{}))
+	mine := &document{OwnerID: 1, Title: "mine"}
+	if err := base.Create(mine); err != nil {
+		t.Fatal(err)
+	}
+	repo := base.WithPolicy(ownerPolicy{}).WithContext(crud.WithActor(context.Background(), &actor{ID: 1}))
+
+	// 修改 owner 把记录转给他人被拒绝并回滚
+	if _, _, err := repo.Patch(mine.ID, map[string]interface{}{"owner_id": 2, "title": "given"}, nil); !crud.IsForbidden(err) {
+		t.Fatalf("Patch owner err = %v", err)
+	}
+	if got, err := base.FindByID(mine.ID); err != nil || got.OwnerID != 1 || got.Title != "mine" {
+		t.Fatalf("mine = %+v, %v", got, err)
+	}
+	if got, changed, err := repo.Patch(mine.ID, map[string]interface{}{"title": "changed"}, nil); err != nil || got.Title != "changed" || len(changed) != 1 {
+		t.Fatalf("Patch = %+v %v, %v", got, changed, err)
+	}
+}
+
+func TestForbiddenError(t *testing.T) {
+	for _, err := range []error{
+		&crud.ForbiddenError{Table: "orders", Action: crud.ActionDelete},
+		crud.ErrNoActor,
+		fmt.Errorf("wrapped: %w", crud.ErrNoActor),
+	} {
+		var sc interface{ StatusCode() int }
+		if !crud.IsForbidden(err) || !errors.As(err, &sc) || sc.StatusCode() != 403 {
+			t.Fatalf("%v: not a 403 ForbiddenError", err)
+		}
+	}
+	if crud.IsForbidden(gorm.ErrRecordNotFound) {
+		t.Fatal("ErrRecordNotFound is not forbidden")
+	}
+}
+
+// adminTreePolicy 隐藏名为 hidden 的节点，只有管理员可以写
+type adminTreePolicy struct{}
+
+func (adminTreePolicy) Scope(ctx context.Context) (crud.QueryFunc, error) {
+	return func(db *gorm.DB) *gorm.DB { return db.Where("name <> ?", "hidden") }, nil
+}
+
+func (adminTreePolicy) Allow(ctx context.Context, action crud.Action, c *category) (bool, error) {
+	a, ok := crud.ActorFrom[*actor](ctx)
+	return ok && a.Admin, nil
+}
+
+func TestPolicy_Tree(t *testing.T) {
+	base := crud.NewTreeRepository(&category{}, newTestDB(t, &category{}))
+	root := &category{Name: "root"}
+	hidden := &category{Name: "hidden"}
+	for _, c := range []*category{root, hidden} {
+		if err := base.CreateNode(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo := base.WithPolicy(adminTreePolicy{})
+	user := repo.WithContext(crud.WithActor(context.Background(), &actor{ID: 1}))
+	admin := repo.WithContext(crud.WithActor(context.Background(), &actor{ID: 2, Admin: true}))
+
+	if err := user.CreateNode(&category{Name: "child"}); !crud.IsForbidden(err) {
+		t.Fatalf("CreateNode err = %v", err)
+	}
+	if err := admin.CreateNode(&category{Name: "child", TreeModel: crud.TreeModel{ParentID: hidden.ID}}); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("CreateNode under hidden parent err = %v", err)
+	}
+	child := &category{Name: "child", TreeModel: crud.TreeModel{ParentID: root.ID}}
+	if err := admin.CreateNode(child); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := user.Move(child.ID, 0); !crud.IsForbidden(err) {
+		t.Fatalf("Move err = %v", err)
+	}
+	if err := admin.Move(hidden.ID, root.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("Move hidden node err = %v", err)
+	}
+	if _, err := admin.Descendants(hidden.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("Descendants of hidden node err = %v", err)
+	}
+	if err := admin.Move(child.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// frozenTreePolicy 名称以 frozen 开头的节点不能修改
+type frozenTreePolicy struct{}
+
+func (frozenTreePolicy) Scope(ctx context.Context) (crud.QueryFunc, error) { return nil, nil }
+
+func (frozenTreePolicy) Allow(ctx context.Context, action crud.Action, c *category) (bool, error) {
+	return !strings.HasPrefix(c.Name, "frozen"), nil
+}
+
+func TestPolicy_TreeMove(t *testing.T) {
+	base := crud.NewTreeRepository(&category{}, newTestDB(t, &category{}))
+	create := func(name string, parent uint) *category {
+		c := &category{Name: name, TreeModel: crud.TreeModel{ParentID: parent}}
+		if err := base.CreateNode(c); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	root := create("root", 0)
+	a := create("a", root.ID)
+	create("frozen child", a.ID)
+	b := create("b", root.ID)
+	frozen := create("frozen parent", root.ID)
+
+	repo := base.WithPolicy(frozenTreePolicy{})
+	// 子树中有不能修改的节点
+	if err := repo.Move(a.ID, b.ID); !crud.IsForbidden(err) {
+		t.Fatalf("Move subtree err = %v", err)
+	}
+	// 新的父节点不能修改
+	if err := repo.Move(b.ID, frozen.ID); !crud.IsForbidden(err) {
+		t.Fatalf("Move under frozen parent err = %v", err)
+	}
+	if err := repo.Move(b.ID, a.ID); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,11 +1,16 @@
 package crud
 
 import (
+	"context"
+	"errors"
+	"iter"
 	"reflect"
 	"strings"
 
 	"github.com/lazyfury/bowlutils/crud/internal/condition"
+	bowldb "github.com/lazyfury/bowlutils/db"
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
 type TableName interface {
@@ -18,8 +23,10 @@ type Model interface {
 }
 
 type Repository[T Model] struct {
-	db    *gorm.DB
-	model T
+	db     *gorm.DB
+	model  T
+	policy Policy[T]
+	scopes []QueryFunc
 }
 
 func NewRepository[T Model](model T, db *gorm.DB) *Repository[T] {
@@ -31,32 +38,63 @@ func NewRepository[T Model](model T, db *gorm.DB) *Repository[T] {
 
 func (r *Repository[T]) FindByID(id uint) (T, error) {
 	var model T
-	if err := r.db.Where("id = ?", id).First(&model).Error; err != nil {
+	opts, err := r.scoped(nil)
+	if err != nil {
+		return model, err
+	}
+	db := r.conn().Where("id = ?", id)
+	for _, opt := range opts {
+		db = opt(db)
+	}
+	if err := db.First(&model).Error; err != nil {
 		return model, err
 	}
 	return model, nil
 }
 
-// query
+// query，不经过 Policy
 func (r *Repository[T]) Query(kvs map[string]interface{}) *gorm.DB {
-	return r.db.Table(r.model.TableName()).Where(kvs)
+	return r.conn().Table(r.model.TableName()).Where(kvs)
 }
 
-// db
+// db，不经过 Policy
 func (r *Repository[T]) DB() *gorm.DB {
-	return r.db.Table(r.model.TableName())
+	return r.conn().Table(r.model.TableName())
 }
 
 // tx
+// 事务会放入 tx.Statement.Context，其他仓储通过 WithContext(tx.Statement.Context) 加入同一事务；
+// 已处于 db.WithTx 事务中时使用 savepoint
 func (r *Repository[T]) Tx(fn func(db *gorm.DB) error) error {
-	return r.db.Table(r.model.TableName()).Transaction(fn)
+	return bowldb.WithTx(r.context(), r.db, func(ctx context.Context) error {
+		tx, _ := bowldb.TxFor(ctx, r.db)
+		return fn(tx.Table(r.model.TableName()))
+	})
 }
 
 type QueryFunc func(db *gorm.DB) *gorm.DB
 
+// conn context 中有 db.WithTx 开启的事务时使用该事务
+func (r *Repository[T]) conn() *gorm.DB {
+	ctx := r.context()
+	if tx, ok := bowldb.TxFor(ctx, r.db); ok {
+		return tx
+	}
+	return r.db
+}
+
+// scope 绑定模型与表名，Count 等不带目标结构体的查询也能应用软删除条件
+func (r *Repository[T]) scope() *gorm.DB {
+	return r.conn().Model(r.model).Table(r.model.TableName())
+}
+
 // list by deleted_at
 func (r *Repository[T]) List(out any, opts ...QueryFunc) error {
-	db := r.db.Table(r.model.TableName())
+	opts, err := r.scoped(opts)
+	if err != nil {
+		return err
+	}
+	db := r.scope()
 	for _, opt := range opts {
 		db = opt(db)
 	}
@@ -67,6 +105,7 @@ func (r *Repository[T]) List(out any, opts ...QueryFunc) error {
 }
 
 // page
+// 默认精确统计总数，可通过 WithoutTotal / WithTotalCap / WithEstimatedTotal 调整
 func (r *Repository[T]) Page(out any, page, pageSize int, opts ...QueryFunc) (Page[T], error) {
 	if page <= 0 {
 		page = 1
@@ -75,32 +114,207 @@ func (r *Repository[T]) Page(out any, page, pageSize int, opts ...QueryFunc) (Pa
 		pageSize = 10
 	}
 
-	db := r.db.Table(r.model.TableName())
+	opts, err := r.scoped(opts)
+	if err != nil {
+		return Page[T]{}, err
+	}
+	db := r.scope()
 	for _, opt := range opts {
 		db = opt(db)
 	}
-	var total int64
-	if err := db.Count(&total).Error; err != nil {
+	total, exact, err := r.pageTotal(db)
+	if err != nil {
 		return Page[T]{}, err
 	}
-	if err := db.Offset((page - 1) * pageSize).Limit(pageSize).Find(out).Error; err != nil {
+
+	limit := pageSize
+	if !exact {
+		// 总数未统计或不精确时多取一条判断是否有下一页
+		limit = pageSize + 1
+	}
+	if err := db.Offset((page - 1) * pageSize).Limit(limit).Find(out).Error; err != nil {
 		return Page[T]{}, err
 	}
+	items := (any)(out).(*[]T)
+
+	result := Page[T]{
+		PageNum:    int64(page),
+		PageSize:   int64(pageSize),
+		Total:      total,
+		TotalExact: exact,
+		Items:      items,
+	}
+	if total < 0 {
+		result.Total = 0
+	} else {
+		result.PageCount = (total + int64(pageSize) - 1) / int64(pageSize)
+	}
+	if exact {
+		result.HasNext = int64(page) < result.PageCount
+		return result, nil
+	}
+	result.HasNext = len(*items) > pageSize
+	if result.HasNext {
+		*items = (*items)[:pageSize]
+	}
+	return result, nil
+}
+
+// pageTotal 按 db 中的 TotalMode 统计总数，total < 0 表示不统计
+func (r *Repository[T]) pageTotal(db *gorm.DB) (total int64, exact bool, err error) {
+	opt := pageTotalOption(db)
+	switch opt.mode {
+	case TotalModeNone:
+		return -1, false, nil
+	case TotalModeCapped:
+		if opt.cap <= 0 {
+			break
+		}
+		sub := db.Session(&gorm.Session{}).Model(r.model).Select("1").Limit(int(opt.cap) + 1)
+		if err := r.conn().Table("(?) AS capped", sub).Count(&total).Error; err != nil {
+			return 0, false, err
+		}
+		if total > opt.cap {
+			return opt.cap, false, nil
+		}
+		return total, true, nil
+	case TotalModeEstimate:
+		// 有过滤条件时估算值没有意义，退回精确统计；
+		// 软删除条件在执行时才加入 WHERE，估算值会包括已删除的记录，同样退回精确统计
+		if _, filtered := db.Statement.Clauses["WHERE"]; filtered {
+			break
+		}
+		if soft, err := r.softDelete(); err != nil || soft && !db.Statement.Unscoped {
+			break
+		}
+		if total, ok := r.estimateTotal(); ok {
+			return total, false, nil
+		}
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return 0, false, err
+	}
+	return total, true, nil
+}
+
+// schema 解析后的模型结构
+func (r *Repository[T]) schema() (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: r.db}
+	if err := stmt.Parse(r.model); err != nil {
+		return nil, err
+	}
+	return stmt.Schema, nil
+}
+
+// softDelete 模型是否有 gorm.DeletedAt 字段
+func (r *Repository[T]) softDelete() (bool, error) {
+	s, err := r.schema()
+	if err != nil {
+		return false, err
+	}
+	for _, f := range s.Fields {
+		if f.FieldType == reflect.TypeOf(gorm.DeletedAt{}) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// estimateTotal 读取数据库统计信息中的行数估算值（Postgres/MySQL）
+func (r *Repository[T]) estimateTotal() (int64, bool) {
+	var estimate *float64
+	var err error
+	switch r.conn().Dialector.Name() {
+	case "postgres":
+		err = r.conn().Raw("SELECT reltuples FROM pg_class WHERE relname = ?", r.model.TableName()).Scan(&estimate).Error
+	case "mysql":
+		err = r.conn().Raw("SELECT table_rows FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?", r.model.TableName()).Scan(&estimate).Error
+	default:
+		return 0, false
+	}
+	// postgres 未 ANALYZE 过的表 reltuples 为 -1
+	if err != nil || estimate == nil || *estimate < 0 {
+		return 0, false
+	}
+	return int64(*estimate), true
+}
+
+// DefaultChunkSize Chunk/Each 未指定 size 时的批大小
+var DefaultChunkSize = 100
+
+// errStopIteration Each 的调用方提前结束遍历
+var errStopIteration = errors.New("crud: stop iteration")
+
+// chunk 按主键 keyset 分页遍历，opts 中不应包含排序，fn 返回错误时立即停止并返回该错误
+func (r *Repository[T]) Chunk(size int, fn func([]T) error, opts ...QueryFunc) error {
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	opts, err := r.scoped(opts)
+	if err != nil {
+		return err
+	}
+	idKey := r.model.TableName() + ".id"
+	var lastID uint
+	for {
+		var items []T
+		db := r.scope().Where(idKey+" > ?", lastID).Order(idKey + " asc")
+		for _, opt := range opts {
+			db = opt(db)
+		}
+		if err := db.Limit(size).Find(&items).Error; err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		if err := fn(items); err != nil {
+			return err
+		}
+		if len(items) < size {
+			return nil
+		}
+		lastID = items[len(items)-1].GetID()
+	}
+}
 
-	return Page[T]{
-		PageNum:   int64(page),
-		PageSize:  int64(pageSize),
-		PageCount: (total + int64(pageSize) - 1) / int64(pageSize),
-		Total:     total,
-		Items:     (any)(out).(*[]T),
-	}, nil
+// each 逐条遍历的迭代器，底层使用 Chunk，查询出错时 yield 零值与错误后结束
+//
+//	for item, err := range repo.Each(500) {
+//		if err != nil {
+//			return err
+//		}
+//	}
+func (r *Repository[T]) Each(size int, opts ...QueryFunc) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		err := r.Chunk(size, func(items []T) error {
+			for _, item := range items {
+				if !yield(item, nil) {
+					return errStopIteration
+				}
+			}
+			return nil
+		}, opts...)
+		if err != nil && !errors.Is(err, errStopIteration) {
+			var zero T
+			yield(zero, err)
+		}
+	}
 }
 
 // exists
 func (r *Repository[T]) Exists(id uint) (bool, error) {
 	var model = r.model
 	var count int64
-	if err := r.db.Model(&model).Where("id = ?", id).Where(model.DeletedAtKey(), "IS NULL").Count(&count).Error; err != nil {
+	opts, err := r.scoped(nil)
+	if err != nil {
+		return false, err
+	}
+	db := r.conn().Model(&model).Where("id = ?", id).Where(model.DeletedAtKey() + " IS NULL")
+	for _, opt := range opts {
+		db = opt(db)
+	}
+	if err := db.Count(&count).Error; err != nil {
 		return false, err
 	}
 	return count > 0, nil
@@ -120,7 +334,10 @@ func (r *Repository[T]) AssetExists(id uint) error {
 
 // create
 func (r *Repository[T]) Create(model T) error {
-	if err := r.db.Create(&model).Error; err != nil {
+	if err := r.authorize(ActionCreate, model); err != nil {
+		return err
+	}
+	if err := r.conn().Create(&model).Error; err != nil {
 		return err
 	}
 	return nil
@@ -128,33 +345,29 @@ func (r *Repository[T]) Create(model T) error {
 
 // updates
 func (r *Repository[T]) Updates(model T) error {
-	if err := r.AssetExists(model.GetID()); err != nil {
+	if err := r.authorizeUpdate(model); err != nil {
 		return err
 	}
-	if err := r.db.Updates(&model).Error; err != nil {
+	if err := r.conn().Updates(&model).Error; err != nil {
 		return err
 	}
 	return nil
 }
 
-// update
-func (r *Repository[T]) Update(key string, value interface{}) error {
-	var model T
-	if err := r.AssetExists(model.GetID()); err != nil {
-		return err
-	}
-	if err := r.db.Model(&model).Where("id = ?", model.GetID()).Update(key, value).Error; err != nil {
+// update 更新 id 对应记录的单个列，不经过 Policy
+func (r *Repository[T]) Update(id uint, key string, value interface{}) error {
+	if err := r.AssetExists(id); err != nil {
 		return err
 	}
-	return nil
+	return r.conn().Model(r.newModel()).Where("id = ?", id).Update(key, value).Error
 }
 
 // save
 func (r *Repository[T]) Save(model T) error {
-	if err := r.AssetExists(model.GetID()); err != nil {
+	if err := r.authorizeUpdate(model); err != nil {
 		return err
 	}
-	if err := r.db.Save(&model).Error; err != nil {
+	if err := r.conn().Save(&model).Error; err != nil {
 		return err
 	}
 	return nil
@@ -162,13 +375,32 @@ func (r *Repository[T]) Save(model T) error {
 
 // delete by id (soft delete if model has DeletedAt)
 func (r *Repository[T]) DeleteByID(id uint) error {
+	if r.policy != nil {
+		target, err := r.FindByID(id)
+		if err != nil {
+			return err
+		}
+		if err := r.authorize(ActionDelete, target); err != nil {
+			return err
+		}
+	}
 	m := r.model
-	if err := r.db.Table(m.TableName()).Where("id = ?", id).Delete(&m).Error; err != nil {
+	if err := r.conn().Table(m.TableName()).Where("id = ?", id).Delete(&m).Error; err != nil {
 		return err
 	}
 	return nil
 }
 
+// new model instance, T 为指针类型时分配新的结构体
+func (r *Repository[T]) newModel() T {
+	rType := reflect.TypeOf(r.model)
+	if rType.Kind() == reflect.Ptr {
+		return reflect.New(rType.Elem()).Interface().(T)
+	}
+	var model T
+	return model
+}
+
 // reflect all model field key
 func (r *Repository[T]) ReflectKeys() []string {
 	var keys []string
@@ -282,7 +514,7 @@ func (r *Repository[T]) MapToSearch(params map[string]interface{}) []QueryFunc {
 			table = strs[0]
 			key = strings.Join(strs[1:], "_")
 			fns = append(fns, func(db *gorm.DB) *gorm.DB {
-				return condition.FKAct(db, fKey, table, key, v)
+				return condition.FKAct(withFilterKey(db, k), fKey, table, key, v)
 			})
 
 			continue
@@ -295,7 +527,7 @@ func (r *Repository[T]) MapToSearch(params map[string]interface{}) []QueryFunc {
 				continue
 			}
 			fns = append(fns, func(db *gorm.DB) *gorm.DB {
-				return condition.SortAct(db, key, sortAction)
+				return condition.SortAct(withFilterKey(db, k), key, sortAction)
 			})
 
 			continue
@@ -306,7 +538,7 @@ func (r *Repository[T]) MapToSearch(params map[string]interface{}) []QueryFunc {
 				continue
 			}
 			fns = append(fns, func(db *gorm.DB) *gorm.DB {
-				return action.Action()(db, key, v)
+				return action.Action()(withFilterKey(db, k), key, v)
 			})
 		}
 	}
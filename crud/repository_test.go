@@ -0,0 +1,370 @@
+package crud_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lazyfury/bowlutils/crud"
+	"github.com/lazyfury/bowlutils/db"
+	"gorm.io/gorm"
+)
+
+type user struct {
+	crud.BaseModel
+	Name   string  `json:"name"`
+	Age    int     `json:"age"`
+	Active bool    `json:"active"`
+	Note   *string `json:"note"`
+}
+
+func (u *user) TableName() string { return "users" }
+
+func newTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+	conn, err := db.Open(db.DBConfig{Driver: db.DriverSQLite, DSN: db.SQLiteMemory, LogLevel: "silent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		sqlDB, _ := conn.DB()
+		sqlDB.Close()
+	})
+	return conn
+}
+
+func seedUsers(t *testing.T, repo *crud.Repository[*user], names ...string) []*user {
+	t.Helper()
+	var users []*user
+	for i, name := range names {
+		u := &user{Name: name, Age: 20 + i}
+		if err := repo.Create(u); err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, u)
+	}
+	return users
+}
+
+func TestRepository_CRUD(t *testing.T) {
+	repo := crud.NewRepository(&user{}, newTestDB(t, &user{}))
+	users := seedUsers(t, repo, "alice", "bob")
+
+	got, err := repo.FindByID(users[0].ID)
+	if err != nil || got.Name != "alice" {
+		t.Fatalf("FindByID = %+v, %v", got, err)
+	}
+
+	exists, err := repo.Exists(users[1].ID)
+	if err != nil || !exists {
+		t.Fatalf("Exists = %v, %v", exists, err)
+	}
+
+	got.Name = "alice2"
+	if err := repo.Updates(got); err != nil {
+		t.Fatal(err)
+	}
+	// Update 按 id 更新单个列
+	if err := repo.Update(users[1].ID, "age", 40); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repo.FindByID(users[1].ID); err != nil || got.Age != 40 || got.Name != "bob" {
+		t.Fatalf("Update = %+v, %v", got, err)
+	}
+	if err := repo.Update(999, "age", 1); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("Update missing row err = %v", err)
+	}
+
+	if err := repo.DeleteByID(users[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := repo.Exists(users[1].ID); exists {
+		t.Fatal("soft deleted row should not exist")
+	}
+	if _, err := repo.FindByID(users[1].ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("FindByID on deleted row: %v", err)
+	}
+
+	page, err := repo.Page(&[]*user{}, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 1 || len(*page.Items) != 1 || (*page.Items)[0].Name != "alice2" {
+		t.Fatalf("Page = %+v", page)
+	}
+}
+
+func TestRepository_Search(t *testing.T) {
+	repo := crud.NewRepository(&user{}, newTestDB(t, &user{}))
+	users := seedUsers(t, repo, "Alice", "alfred", "bob")
+	note := "vip"
+	users[2].Note = &note
+	if err := repo.Updates(users[2]); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		params map[string]string
+		want   []string
+	}{
+		// LIKE 区分大小写，与 Postgres 一致
+		{map[string]string{"name__like": "al", "age__sort": "asc"}, []string{"alfred"}},
+		{map[string]string{"name__like": "Al"}, []string{"Alice"}},
+		{map[string]string{"name__not_like": "al", "age__sort": "asc"}, []string{"Alice", "bob"}},
+		{map[string]string{"name__like_right": "b"}, []string{"bob"}},
+		{map[string]string{"name__in": "bob"}, []string{"bob"}},
+		{map[string]string{"name__in": "bob,Alice", "age__sort": "desc"}, []string{"bob", "Alice"}},
+		{map[string]string{"name__not_in": "bob", "age__sort": "asc"}, []string{"Alice", "alfred"}},
+		{map[string]string{"age__gte": "21", "age__sort": "asc"}, []string{"alfred", "bob"}},
+		{map[string]string{"note__is_null": "1", "age__sort": "desc"}, []string{"alfred", "Alice"}},
+		// NULL 视为最大值，与 Postgres 一致
+		{map[string]string{"name__in": "bob,Alice", "note__sort": "asc"}, []string{"bob", "Alice"}},
+		{map[string]string{"name__in": "bob,Alice", "note__sort": "desc"}, []string{"Alice", "bob"}},
+	}
+	for _, tt := range tests {
+		var out []*user
+		if err := repo.List(&out, repo.QueryParamsToSearch(tt.params)...); err != nil {
+			t.Fatalf("%v: %v", tt.params, err)
+		}
+		var names []string
+		for _, u := range out {
+			names = append(names, u.Name)
+		}
+		if len(names) != len(tt.want) {
+			t.Errorf("%v: got %v, want %v", tt.params, names, tt.want)
+			continue
+		}
+		for i := range names {
+			if names[i] != tt.want[i] {
+				t.Errorf("%v: got %v, want %v", tt.params, names, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestRepository_PageTotals(t *testing.T) {
+	repo := crud.NewRepository(&user{}, newTestDB(t, &user{}))
+	seedUsers(t, repo, "a", "b", "c", "d", "e")
+
+	page, err := repo.Page(&[]*user{}, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 5 || !page.TotalExact || page.PageCount != 3 || !page.HasNext {
+		t.Fatalf("exact page = %+v", page)
+	}
+
+	page, err = repo.Page(&[]*user{}, 3, 2, crud.WithoutTotal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.TotalExact || page.HasNext || len(*page.Items) != 1 {
+		t.Fatalf("without total page = %+v", page)
+	}
+	page, err = repo.Page(&[]*user{}, 1, 2, crud.WithoutTotal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !page.HasNext || len(*page.Items) != 2 {
+		t.Fatalf("without total page = %+v", page)
+	}
+
+	page, err = repo.Page(&[]*user{}, 1, 2, crud.WithTotalCap(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 3 || page.TotalExact || !page.HasNext || len(*page.Items) != 2 {
+		t.Fatalf("capped page = %+v", page)
+	}
+	// 超过上限的页仍根据实际数据判断 has_next
+	page, err = repo.Page(&[]*user{}, 2, 2, crud.WithTotalCap(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 3 || page.PageCount != 2 || !page.HasNext || len(*page.Items) != 2 {
+		t.Fatalf("capped page = %+v", page)
+	}
+	page, err = repo.Page(&[]*user{}, 3, 2, crud.WithTotalCap(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.HasNext || len(*page.Items) != 1 {
+		t.Fatalf("capped page = %+v", page)
+	}
+
+	// sqlite 没有估算信息，退回精确统计
+	page, err = repo.Page(&[]*user{}, 1, 2, crud.WithEstimatedTotal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 5 || !page.TotalExact {
+		t.Fatalf("estimated page = %+v", page)
+	}
+}
+
+func TestRepository_Chunk(t *testing.T) {
+	repo := crud.NewRepository(&user{}, newTestDB(t, &user{}))
+	seedUsers(t, repo, "a", "b", "c", "d", "e")
+
+	var sizes []int
+	if err := repo.Chunk(2, func(items []*user) error {
+		sizes = append(sizes, len(items))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(sizes) != 3 || sizes[2] != 1 {
+		t.Fatalf("chunk sizes = %v", sizes)
+	}
+
+	stop := errors.New("stop")
+	if err := repo.Chunk(2, func([]*user) error { return stop }); !errors.Is(err, stop) {
+		t.Fatalf("Chunk should return callback error, got %v", err)
+	}
+
+	var names []string
+	for u, err := range repo.Each(2) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, u.Name)
+		if len(names) == 3 {
+			break
+		}
+	}
+	if len(names) != 3 || names[2] != "c" {
+		t.Fatalf("Each = %v", names)
+	}
+}
+
+func TestRepository_WithTx(t *testing.T) {
+	conn := newTestDB(t, &user{}, &category{})
+	users := crud.NewRepository(&user{}, conn)
+	categories := crud.NewRepository(&category{}, conn)
+	ctx := context.Background()
+	errRollback := errors.New("rollback")
+
+	var committed []string
+	err := db.WithTx(ctx, conn, func(ctx context.Context) error {
+		if err := users.WithContext(ctx).Create(&user{Name: "alice"}); err != nil {
+			return err
+		}
+		db.AfterCommit(ctx, func() { committed = append(committed, "outer") })
+		// savepoint 回滚不影响外层
+		err := db.WithTx(ctx, conn, func(ctx context.Context) error {
+			if err := categories.WithContext(ctx).Create(&category{Name: "books"}); err != nil {
+				return err
+			}
+			db.AfterCommit(ctx, func() { committed = append(committed, "discarded") })
+			return errRollback
+		})
+		if !errors.Is(err, errRollback) {
+			return err
+		}
+		return db.WithTx(ctx, conn, func(ctx context.Context) error {
+			db.AfterCommit(ctx, func() { committed = append(committed, "inner") })
+			return categories.WithContext(ctx).Create(&category{Name: "music"})
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(committed) != 2 || committed[0] != "outer" || committed[1] != "inner" {
+		t.Fatalf("after commit callbacks = %v", committed)
+	}
+	var cats []*category
+	if err := categories.List(&cats); err != nil || len(cats) != 1 || cats[0].Name != "music" {
+		t.Fatalf("categories = %v, %v", cats, err)
+	}
+
+	// 外层回滚时所有仓储的写入都撤销，回调不执行
+	committed = nil
+	err = db.WithTx(ctx, conn, func(ctx context.Context) error {
+		if err := users.WithContext(ctx).Create(&user{Name: "bob"}); err != nil {
+			return err
+		}
+		if err := categories.WithContext(ctx).Create(&category{Name: "games"}); err != nil {
+			return err
+		}
+		db.AfterCommit(ctx, func() { committed = append(committed, "never") })
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) || len(committed) != 0 {
+		t.Fatalf("err = %v, callbacks = %v", err, committed)
+	}
+	var all []*user
+	if err := users.List(&all); err != nil || len(all) != 1 {
+		t.Fatalf("users = %v, %v", all, err)
+	}
+
+	// Repository.Tx 把事务放入 Statement.Context，其他仓储可以加入
+	err = users.Tx(func(tx *gorm.DB) error {
+		if err := tx.Create(&user{Name: "carol"}).Error; err != nil {
+			return err
+		}
+		if err := categories.WithContext(tx.Statement.Context).Create(&category{Name: "films"}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatal(err)
+	}
+	if err := categories.List(&cats); err != nil || len(cats) != 1 {
+		t.Fatalf("category created in Repository.Tx should be rolled back, got %v, %v", cats, err)
+	}
+}
+
+func TestRepository_WithTx_MultiDB(t *testing.T) {
+	mainDB := newTestDB(t, &user{})
+	analyticsDB := newTestDB(t, &user{})
+	users := crud.NewRepository(&user{}, mainDB)
+	events := crud.NewRepository(&user{}, analyticsDB)
+	ctx := context.Background()
+	errRollback := errors.New("rollback")
+
+	// 其他数据库的仓储不加入当前事务，写入各自的库
+	var committed []string
+	err := db.WithTx(ctx, mainDB, func(ctx context.Context) error {
+		if err := users.WithContext(ctx).Create(&user{Name: "alice"}); err != nil {
+			return err
+		}
+		if err := events.WithContext(ctx).Create(&user{Name: "signup"}); err != nil {
+			return err
+		}
+		// 嵌套的其他数据库事务独立提交，外层事务在 ctx 中保持可见
+		err := db.WithTx(ctx, analyticsDB, func(ctx context.Context) error {
+			db.AfterCommit(ctx, func() { committed = append(committed, "analytics") })
+			if err := users.WithContext(ctx).Create(&user{Name: "bob"}); err != nil {
+				return err
+			}
+			return events.WithContext(ctx).Create(&user{Name: "login"})
+		})
+		if err != nil {
+			return err
+		}
+		if len(committed) != 1 {
+			t.Errorf("analytics tx should commit on its own, callbacks = %v", committed)
+		}
+		db.AfterCommit(ctx, func() { committed = append(committed, "main") })
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatal(err)
+	}
+	if len(committed) != 1 || committed[0] != "analytics" {
+		t.Fatalf("after commit callbacks = %v", committed)
+	}
+
+	var out []*user
+	if err := users.List(&out); err != nil || len(out) != 0 {
+		t.Fatalf("main users = %v, %v", out, err)
+	}
+	if err := events.List(&out); err != nil || len(out) != 2 {
+		t.Fatalf("analytics users = %v, %v", out, err)
+	}
+}
@@ -0,0 +1,406 @@
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	bowldb "github.com/lazyfury/bowlutils/db"
+	"go.yaml.in/yaml/v3"
+	"gorm.io/gorm"
+)
+
+/*
+Seeder 使用示例:
+
+	// fixtures/users.yaml
+	users:
+	  alice:
+	    name: Alice
+	    email: alice@example.com
+
+	// fixtures/posts.yaml
+	posts:
+	  hello:
+	    title: Hello
+	    author_id: $users.alice   # 引用 users 表中 alice 记录的 ID
+
+	seeder := crud.NewSeeder(db).
+		Register(crud.NewRepository(&User{}, db), "email"). // 以 email 判断记录是否已存在
+		Register(crud.NewRepository(&Post{}, db), "title")
+	if err := seeder.LoadDir("fixtures"); err != nil {
+		return err
+	}
+	ids, err := seeder.Run() // ids["users.alice"] => 1
+
+	// 仓储配置了 Policy 时，通过 ctx 传入操作者
+	ids, err = seeder.RunContext(crud.WithActor(ctx, admin))
+*/
+
+// SeedRefPrefix 引用其他记录 ID 的前缀，$table.label
+const SeedRefPrefix = "$"
+
+var (
+	// ErrSeedCycle 记录之间存在循环引用
+	ErrSeedCycle = errors.New("crud: circular reference in fixtures")
+	// ErrSeedKey 表未注册 upsertKeys 且记录没有 id，重复执行会插入重复记录
+	ErrSeedKey = errors.New("crud: fixture has no upsert key or id")
+)
+
+// Fixtures 种子数据 table -> label -> row
+type Fixtures map[string]map[string]map[string]interface{}
+
+// Seedable 可被 Seeder 写入的仓储，*Repository[T] 已实现；
+// SeedUpsert 的 ctx 中带有 Seeder 开启的事务（db.WithTx）
+type Seedable interface {
+	SeedTable() string
+	SeedUpsert(ctx context.Context, row map[string]interface{}, keys []string) (uint, error)
+}
+
+// Seeder 读取 fixtures 并按依赖顺序在同一事务中写入
+type Seeder struct {
+	db       *gorm.DB
+	repos    map[string]Seedable
+	keys     map[string][]string
+	fixtures Fixtures
+}
+
+func NewSeeder(db *gorm.DB) *Seeder {
+	return &Seeder{
+		db:       db,
+		repos:    make(map[string]Seedable),
+		keys:     make(map[string][]string),
+		fixtures: make(Fixtures),
+	}
+}
+
+// Register 注册表对应的仓储，upsertKeys 为判断记录是否已存在的列；
+// 未指定时使用 fixture 中的 id，没有 id 的记录返回 ErrSeedKey
+func (s *Seeder) Register(repo Seedable, upsertKeys ...string) *Seeder {
+	table := repo.SeedTable()
+	s.repos[table] = repo
+	s.keys[table] = upsertKeys
+	return s
+}
+
+// Add 合并 fixtures，同名 label 后加入的覆盖先加入的
+func (s *Seeder) Add(fixtures Fixtures) *Seeder {
+	for table, rows := range fixtures {
+		if s.fixtures[table] == nil {
+			s.fixtures[table] = make(map[string]map[string]interface{})
+		}
+		for label, row := range rows {
+			s.fixtures[table][label] = row
+		}
+	}
+	return s
+}
+
+// LoadFile 加载 .yaml/.yml/.json 文件
+func (s *Seeder) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var fixtures Fixtures
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &fixtures)
+	case ".json":
+		err = json.Unmarshal(data, &fixtures)
+	default:
+		return fmt.Errorf("crud: unsupported fixture file: %s", path)
+	}
+	if err != nil {
+		return fmt.Errorf("crud: parse fixture %s: %w", path, err)
+	}
+	s.Add(fixtures)
+	return nil
+}
+
+// LoadDir 加载目录下所有 fixture 文件（按文件名排序）
+func (s *Seeder) LoadDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(e.Name())) {
+		case ".yaml", ".yml", ".json":
+			if err := s.LoadFile(filepath.Join(dir, e.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Run 在一个事务中按依赖顺序写入所有记录，返回 table.label => ID
+// 已存在的记录（按 upsertKeys 匹配）会被更新，因此可以重复执行
+func (s *Seeder) Run() (map[string]uint, error) {
+	return s.RunContext(context.Background())
+}
+
+// RunContext 同 Run，ctx 会传递给仓储与 Policy
+func (s *Seeder) RunContext(ctx context.Context) (map[string]uint, error) {
+	order, err := s.order()
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]uint, len(order))
+	err = bowldb.WithTx(ctx, s.db, func(ctx context.Context) error {
+		for _, ref := range order {
+			table, label, _ := strings.Cut(ref, ".")
+			repo := s.repos[table]
+			row, err := resolveSeedRefs(s.fixtures[table][label], ids)
+			if err != nil {
+				return fmt.Errorf("crud: seed %s: %w", ref, err)
+			}
+			id, err := repo.SeedUpsert(ctx, row.(map[string]interface{}), s.keys[table])
+			if err != nil {
+				return fmt.Errorf("crud: seed %s: %w", ref, err)
+			}
+			ids[ref] = id
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// order 对所有记录做拓扑排序，被引用的记录排在前面
+func (s *Seeder) order() ([]string, error) {
+	var refs []string
+	deps := make(map[string][]string)
+	for table, rows := range s.fixtures {
+		if _, ok := s.repos[table]; !ok {
+			return nil, fmt.Errorf("crud: no repository registered for table %s", table)
+		}
+		for label, row := range rows {
+			ref := table + "." + label
+			refs = append(refs, ref)
+			deps[ref] = collectSeedRefs(row, nil)
+		}
+	}
+	sort.Strings(refs)
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(refs))
+	order := make([]string, 0, len(refs))
+	var visit func(ref string) error
+	visit = func(ref string) error {
+		switch state[ref] {
+		case visiting:
+			return fmt.Errorf("%w: %s", ErrSeedCycle, ref)
+		case visited:
+			return nil
+		}
+		state[ref] = visiting
+		for _, dep := range deps[ref] {
+			if _, ok := deps[dep]; !ok {
+				return fmt.Errorf("crud: unknown reference %s%s in %s", SeedRefPrefix, dep, ref)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[ref] = visited
+		order = append(order, ref)
+		return nil
+	}
+	for _, ref := range refs {
+		if err := visit(ref); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// parseSeedRef $users.alice => users.alice, $$ 开头为转义的普通字符串
+func parseSeedRef(v interface{}) (string, bool) {
+	str, ok := v.(string)
+	if !ok || !strings.HasPrefix(str, SeedRefPrefix) || strings.HasPrefix(str, SeedRefPrefix+SeedRefPrefix) {
+		return "", false
+	}
+	ref := strings.TrimPrefix(str, SeedRefPrefix)
+	if !strings.Contains(ref, ".") {
+		return "", false
+	}
+	return ref, true
+}
+
+func collectSeedRefs(v interface{}, refs []string) []string {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			refs = collectSeedRefs(t[k], refs)
+		}
+	case []interface{}:
+		for _, item := range t {
+			refs = collectSeedRefs(item, refs)
+		}
+	default:
+		if ref, ok := parseSeedRef(v); ok {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// resolveSeedRefs 返回替换引用后的副本
+func resolveSeedRefs(v interface{}, ids map[string]uint) (interface{}, error) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, item := range t {
+			resolved, err := resolveSeedRefs(item, ids)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = resolved
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, item := range t {
+			resolved, err := resolveSeedRefs(item, ids)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	case string:
+		if ref, ok := parseSeedRef(t); ok {
+			id, ok := ids[ref]
+			if !ok {
+				return nil, fmt.Errorf("unresolved reference %s", t)
+			}
+			return id, nil
+		}
+		if strings.HasPrefix(t, SeedRefPrefix+SeedRefPrefix) {
+			return strings.TrimPrefix(t, SeedRefPrefix), nil
+		}
+		return t, nil
+	default:
+		return v, nil
+	}
+}
+
+// SeedTable 实现 Seedable
+func (r *Repository[T]) SeedTable() string {
+	return r.model.TableName()
+}
+
+// SeedUpsert 实现 Seedable，keys 匹配到已有记录（包括软删除的）时恢复并更新，否则通过 Create 创建；
+// 记录的查找不受 WithScope 与 Policy 的读取条件限制，以免重复插入读取范围外的记录，写入仍经过 Policy
+func (r *Repository[T]) SeedUpsert(ctx context.Context, row map[string]interface{}, keys []string) (uint, error) {
+	repo := r.WithContext(ctx)
+	return repo.seedUpsert(row, keys, repo.Create, func(existing T, changes map[string]interface{}) error {
+		_, _, err := repo.patch(existing.GetID(), changes, nil)
+		return err
+	})
+}
+
+// seedUpsert 按 keys 查找记录，不存在时以 create 创建，存在时恢复并以 update 写入除 id 外的字段
+func (r *Repository[T]) seedUpsert(row map[string]interface{}, keys []string, create func(T) error, update func(T, map[string]interface{}) error) (uint, error) {
+	if err := r.checkSeedKeys(row); err != nil {
+		return 0, err
+	}
+	if len(keys) == 0 {
+		if _, ok := row["id"]; !ok {
+			return 0, ErrSeedKey
+		}
+		keys = []string{"id"}
+	}
+	where := make(map[string]interface{}, len(keys))
+	for _, k := range keys {
+		v, ok := row[k]
+		if !ok {
+			return 0, fmt.Errorf("missing upsert key %s", k)
+		}
+		where[k] = v
+	}
+
+	var existing []T
+	if err := r.conn().Unscoped().Table(r.model.TableName()).Where(where).Limit(1).Find(&existing).Error; err != nil {
+		return 0, err
+	}
+	if len(existing) == 0 {
+		model, err := r.seedModel(row)
+		if err != nil {
+			return 0, err
+		}
+		if err := create(model); err != nil {
+			return 0, err
+		}
+		return model.GetID(), nil
+	}
+
+	if err := r.restore(existing[0]); err != nil {
+		return 0, err
+	}
+	changes := make(map[string]interface{}, len(row))
+	for k, v := range row {
+		if k != "id" {
+			changes[k] = v
+		}
+	}
+	if err := update(existing[0], changes); err != nil {
+		return 0, err
+	}
+	return existing[0].GetID(), nil
+}
+
+// seedModel 以 JSON 将 row 转换为模型
+func (r *Repository[T]) seedModel(row map[string]interface{}) (T, error) {
+	model := r.newModel()
+	data, err := json.Marshal(row)
+	if err != nil {
+		return model, err
+	}
+	return model, json.Unmarshal(data, &model)
+}
+
+// checkSeedKeys fixture 的 key 必须是模型的列（包括嵌入结构体的列），创建与更新使用同一检查
+func (r *Repository[T]) checkSeedKeys(row map[string]interface{}) error {
+	s, err := r.schema()
+	if err != nil {
+		return err
+	}
+	for k := range row {
+		if _, ok := s.FieldsByDBName[k]; !ok {
+			return fmt.Errorf("%w: %s", ErrPatchField, k)
+		}
+	}
+	return nil
+}
+
+// restore 检查 update 权限后清除软删除标记，未删除的记录不受影响
+func (r *Repository[T]) restore(model T) error {
+	if err := r.authorize(ActionUpdate, model); err != nil {
+		return err
+	}
+	key := r.model.DeletedAtKey()
+	return r.conn().Unscoped().Table(r.model.TableName()).
+		Where("id = ?", model.GetID()).Where(key+" IS NOT NULL").
+		Update(key, nil).Error
+}
@@ -0,0 +1,268 @@
+package crud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	bowldb "github.com/lazyfury/bowlutils/db"
+	"gorm.io/gorm"
+)
+
+type fakeSeedable string
+
+func (f fakeSeedable) SeedTable() string { return string(f) }
+func (f fakeSeedable) SeedUpsert(ctx context.Context, row map[string]interface{}, keys []string) (uint, error) {
+	return 0, nil
+}
+
+type seedUser struct {
+	BaseModel
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func (u *seedUser) TableName() string { return "users" }
+
+type seedPost struct {
+	BaseModel
+	Title    string `json:"title"`
+	AuthorID uint   `json:"author_id"`
+}
+
+func (p *seedPost) TableName() string { return "posts" }
+
+type seedCategory struct {
+	BaseModel
+	TreeModel
+	Name string `json:"name"`
+}
+
+func (c *seedCategory) TableName() string { return "categories" }
+
+// denyPostsPolicy 不允许写入 posts
+type denyPostsPolicy struct{}
+
+func (denyPostsPolicy) Scope(ctx context.Context) (QueryFunc, error) { return nil, nil }
+func (denyPostsPolicy) Allow(ctx context.Context, action Action, post *seedPost) (bool, error) {
+	return false, nil
+}
+
+func newSeedDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	conn, err := bowldb.Open(bowldb.DBConfig{Driver: bowldb.DriverSQLite, DSN: bowldb.SQLiteMemory, LogLevel: "silent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.AutoMigrate(&seedUser{}, &seedPost{}, &seedCategory{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { bowldb.Close(conn) })
+	return conn
+}
+
+func TestSeeder_Order(t *testing.T) {
+	s := NewSeeder(nil).
+		Register(fakeSeedable("users")).
+		Register(fakeSeedable("posts")).
+		Register(fakeSeedable("comments"))
+	s.Add(Fixtures{
+		"comments": {"c1": {"post_id": "$posts.hello", "author_id": "$users.bob"}},
+		"posts":    {"hello": {"author_id": "$users.alice", "title": "$$not a ref"}},
+		"users":    {"alice": {"name": "Alice"}, "bob": {"name": "Bob"}},
+	})
+
+	order, err := s.order()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos := make(map[string]int)
+	for i, ref := range order {
+		pos[ref] = i
+	}
+	if len(order) != 4 {
+		t.Fatalf("order = %v", order)
+	}
+	if pos["users.alice"] > pos["posts.hello"] || pos["posts.hello"] > pos["comments.c1"] || pos["users.bob"] > pos["comments.c1"] {
+		t.Fatalf("dependency order violated: %v", order)
+	}
+}
+
+func TestSeeder_OrderErrors(t *testing.T) {
+	s := NewSeeder(nil).Register(fakeSeedable("nodes"))
+	s.Add(Fixtures{"nodes": {
+		"a": {"parent_id": "$nodes.b"},
+		"b": {"parent_id": "$nodes.a"},
+	}})
+	if _, err := s.order(); !errors.Is(err, ErrSeedCycle) {
+		t.Fatalf("expected ErrSeedCycle, got %v", err)
+	}
+
+	s = NewSeeder(nil).Register(fakeSeedable("nodes"))
+	s.Add(Fixtures{"nodes": {"a": {"parent_id": "$nodes.missing"}}})
+	if _, err := s.order(); err == nil {
+		t.Fatal("expected unknown reference error")
+	}
+
+	s = NewSeeder(nil)
+	s.Add(Fixtures{"nodes": {"a": {}}})
+	if _, err := s.order(); err == nil {
+		t.Fatal("expected unregistered table error")
+	}
+}
+
+func TestResolveSeedRefs(t *testing.T) {
+	ids := map[string]uint{"users.alice": 7}
+	row := map[string]interface{}{
+		"author_id": "$users.alice",
+		"tags":      []interface{}{"$users.alice", "go"},
+		"price":     "$$9.99",
+		"name":      "plain",
+	}
+	got, err := resolveSeedRefs(row, ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := got.(map[string]interface{})
+	if m["author_id"] != uint(7) {
+		t.Errorf("author_id = %v", m["author_id"])
+	}
+	if tags := m["tags"].([]interface{}); tags[0] != uint(7) || tags[1] != "go" {
+		t.Errorf("tags = %v", tags)
+	}
+	if m["price"] != "$9.99" {
+		t.Errorf("price = %v", m["price"])
+	}
+	if row["author_id"] != "$users.alice" {
+		t.Error("resolveSeedRefs must not modify the input")
+	}
+
+	if _, err := resolveSeedRefs(map[string]interface{}{"x": "$users.bob"}, ids); err == nil {
+		t.Error("expected unresolved reference error")
+	}
+}
+
+func TestSeeder_Run(t *testing.T) {
+	conn := newSeedDB(t)
+	users := NewRepository(&seedUser{}, conn)
+	seeder := NewSeeder(conn).
+		Register(users, "name").
+		Register(NewRepository(&seedPost{}, conn), "title")
+	seeder.Add(Fixtures{
+		"users": {"alice": {"name": "alice", "age": 30}},
+		"posts": {"hello": {"title": "hello", "author_id": "$users.alice"}},
+	})
+
+	ids, err := seeder.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 重复执行更新已有记录（包括软删除的），不会插入新记录
+	if err := users.DeleteByID(ids["users.alice"]); err != nil {
+		t.Fatal(err)
+	}
+	seeder.Add(Fixtures{"users": {"alice": {"name": "alice", "age": 31}}})
+	again, err := seeder.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids["users.alice"] != again["users.alice"] || ids["posts.hello"] != again["posts.hello"] {
+		t.Fatalf("seeder is not idempotent: %v vs %v", ids, again)
+	}
+	var count int64
+	conn.Model(&seedUser{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("users count = %d", count)
+	}
+	alice, err := users.FindByID(ids["users.alice"])
+	if err != nil || alice.Age != 31 {
+		t.Fatalf("alice = %+v, %v", alice, err)
+	}
+	post, _ := NewRepository(&seedPost{}, conn).FindByID(ids["posts.hello"])
+	if post.AuthorID != ids["users.alice"] {
+		t.Fatalf("reference not resolved: %+v", post)
+	}
+}
+
+func TestSeeder_RunErrors(t *testing.T) {
+	conn := newSeedDB(t)
+
+	// 没有 upsertKeys 与 id 时无法判断记录是否已存在
+	seeder := NewSeeder(conn).Register(NewRepository(&seedUser{}, conn))
+	seeder.Add(Fixtures{"users": {"alice": {"name": "alice"}}})
+	if _, err := seeder.Run(); !errors.Is(err, ErrSeedKey) {
+		t.Fatalf("expected ErrSeedKey, got %v", err)
+	}
+
+	// 写入经过仓储的 Policy，失败时整个事务回滚
+	seeder = NewSeeder(conn).
+		Register(NewRepository(&seedUser{}, conn), "name").
+		Register(NewRepository(&seedPost{}, conn).WithPolicy(denyPostsPolicy{}), "title")
+	seeder.Add(Fixtures{
+		"users": {"alice": {"name": "alice"}},
+		"posts": {"hello": {"title": "hello", "author_id": "$users.alice"}},
+	})
+	if _, err := seeder.Run(); !IsForbidden(err) {
+		t.Fatalf("expected ForbiddenError, got %v", err)
+	}
+	var count int64
+	conn.Model(&seedUser{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("users should be rolled back, count = %d", count)
+	}
+}
+
+func TestSeeder_RunTree(t *testing.T) {
+	conn := newSeedDB(t)
+	cats := NewTreeRepository(&seedCategory{}, conn)
+	seeder := NewSeeder(conn).Register(cats, "name")
+	seeder.Add(Fixtures{"categories": {
+		"root":  {"name": "root"},
+		"other": {"name": "other"},
+		"child": {"name": "child", "parent_id": "$categories.root"},
+	}})
+	ids, err := seeder.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 第二次执行时 parent_id 等嵌入结构体的列同样可以写入，parent_id 变化时移动节点
+	seeder.Add(Fixtures{"categories": {"child": {"name": "child", "parent_id": "$categories.other"}}})
+	if _, err := seeder.Run(); err != nil {
+		t.Fatal(err)
+	}
+	child, err := cats.FindByID(ids["categories.child"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("/%d/%d/", ids["categories.other"], child.ID)
+	if child.ParentID != ids["categories.other"] || child.Path != want || child.Depth != 1 {
+		t.Fatalf("child = %+v, want path %s", child, want)
+	}
+}
+
+func TestSeeder_RunScoped(t *testing.T) {
+	conn := newSeedDB(t)
+	users := NewRepository(&seedUser{}, conn)
+	if err := users.Create(&seedUser{Name: "hidden"}); err != nil {
+		t.Fatal(err)
+	}
+	// 读取范围外的记录同样按 upsertKeys 匹配，不会重复插入
+	scoped := users.WithScope(func(db *gorm.DB) *gorm.DB { return db.Where("name <> ?", "hidden") })
+	seeder := NewSeeder(conn).Register(scoped, "name")
+	seeder.Add(Fixtures{"users": {"hidden": {"name": "hidden", "age": 5}}})
+	if _, err := seeder.Run(); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	conn.Model(&seedUser{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("users count = %d", count)
+	}
+
+	seeder = NewSeeder(conn).Register(users, "name")
+	seeder.Add(Fixtures{"users": {"bob": {"name": "bob", "nickname": "b"}}})
+	if _, err := seeder.Run(); !errors.Is(err, ErrPatchField) {
+		t.Fatalf("expected ErrPatchField, got %v", err)
+	}
+}
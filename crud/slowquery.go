@@ -0,0 +1,184 @@
+package crud
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	bowldb "github.com/lazyfury/bowlutils/db"
+	"github.com/lazyfury/bowlutils/logger"
+	"gorm.io/gorm"
+)
+
+/*
+SlowQueryPlugin 使用示例:
+
+	db.Use(crud.NewSlowQueryPlugin(crud.SlowQueryConfig{
+		Threshold: 200 * time.Millisecond,
+		Explain:   true, // 慢查询自动附带 EXPLAIN 结果
+	}))
+
+	// 单次查询强制输出 EXPLAIN（不受阈值限制）
+	repo.List(&out, append(repo.QueryParamsToSearch(params), crud.Explain())...)
+*/
+
+const (
+	settingFilterKeys = "crud:filter_keys"
+	settingExplain    = "crud:explain"
+	settingQueryStart = "crud:query_start"
+)
+
+// DefaultSlowQueryThreshold 未设置阈值时使用
+var DefaultSlowQueryThreshold = 200 * time.Millisecond
+
+// SlowQueryConfig 慢查询配置
+type SlowQueryConfig struct {
+	Threshold time.Duration // 超过该耗时记录日志
+	Explain   bool          // 慢查询是否执行 EXPLAIN（仅 MySQL/Postgres 的 SELECT）
+}
+
+// SlowQueryPlugin gorm 插件，记录超过阈值的查询及其过滤字段
+type SlowQueryPlugin struct {
+	config SlowQueryConfig
+}
+
+func NewSlowQueryPlugin(config SlowQueryConfig) *SlowQueryPlugin {
+	if config.Threshold <= 0 {
+		config.Threshold = DefaultSlowQueryThreshold
+	}
+	return &SlowQueryPlugin{config: config}
+}
+
+func (p *SlowQueryPlugin) Name() string {
+	return "crud:slow_query"
+}
+
+// Initialize 注册回调；db 使用 bowldb.GormLogger 时关闭其慢查询日志，慢查询只由插件记录一次
+func (p *SlowQueryPlugin) Initialize(db *gorm.DB) error {
+	if l, ok := db.Logger.(*bowldb.GormLogger); ok {
+		db.Logger = l.WithSlowThreshold(0)
+	}
+	cb := db.Callback()
+	for _, register := range []func() error{
+		func() error { return cb.Query().Before("gorm:query").Register("crud:slow_query_before", p.before) },
+		func() error { return cb.Query().After("gorm:query").Register("crud:slow_query_after", p.after) },
+		func() error { return cb.Row().Before("gorm:row").Register("crud:slow_query_before", p.before) },
+		func() error { return cb.Row().After("gorm:row").Register("crud:slow_query_after", p.after) },
+		func() error { return cb.Create().Before("gorm:create").Register("crud:slow_query_before", p.before) },
+		func() error { return cb.Create().After("gorm:create").Register("crud:slow_query_after", p.after) },
+		func() error { return cb.Update().Before("gorm:update").Register("crud:slow_query_before", p.before) },
+		func() error { return cb.Update().After("gorm:update").Register("crud:slow_query_after", p.after) },
+		func() error { return cb.Delete().Before("gorm:delete").Register("crud:slow_query_before", p.before) },
+		func() error { return cb.Delete().After("gorm:delete").Register("crud:slow_query_after", p.after) },
+	} {
+		if err := register(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *SlowQueryPlugin) before(db *gorm.DB) {
+	db.InstanceSet(settingQueryStart, time.Now())
+}
+
+func (p *SlowQueryPlugin) after(db *gorm.DB) {
+	v, ok := db.InstanceGet(settingQueryStart)
+	if !ok {
+		return
+	}
+	elapsed := time.Since(v.(time.Time))
+	_, forceExplain := db.Get(settingExplain)
+	if elapsed < p.config.Threshold && !forceExplain {
+		return
+	}
+
+	stmt := db.Statement
+	sqlStr := stmt.SQL.String()
+	kv := []interface{}{
+		"table", stmt.Table,
+		"elapsed", elapsed.String(),
+		"sql", sqlStr,
+		"filter_keys", filterKeys(db),
+	}
+	if p.config.Explain || forceExplain {
+		plan, err := explain(db, sqlStr, stmt.Vars)
+		if err != nil {
+			kv = append(kv, "explain_error", err.Error())
+		} else if plan != "" {
+			kv = append(kv, "plan", plan)
+		}
+	}
+	if elapsed >= p.config.Threshold {
+		logger.Warnw("slow query", kv...)
+		return
+	}
+	logger.Infow("query explain", kv...)
+}
+
+// Explain 标记查询，无论耗时都输出 EXPLAIN 结果（需注册 SlowQueryPlugin）
+func Explain() QueryFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Set(settingExplain, true)
+	}
+}
+
+// withFilterKey 记录 MapToSearch 生成的过滤字段，用于慢查询日志
+func withFilterKey(db *gorm.DB, key string) *gorm.DB {
+	keys := filterKeys(db)
+	return db.Set(settingFilterKeys, append(keys[:len(keys):len(keys)], key))
+}
+
+func filterKeys(db *gorm.DB) []string {
+	if v, ok := db.Get(settingFilterKeys); ok {
+		return v.([]string)
+	}
+	return nil
+}
+
+// explain 直接通过连接执行 EXPLAIN，不经过 gorm callbacks
+func explain(db *gorm.DB, sqlStr string, vars []interface{}) (string, error) {
+	switch db.Dialector.Name() {
+	case "mysql", "postgres":
+	default:
+		return "", nil
+	}
+	if !strings.HasPrefix(strings.ToUpper(strings.TrimSpace(sqlStr)), "SELECT") {
+		return "", nil
+	}
+	rows, err := db.Statement.ConnPool.QueryContext(db.Statement.Context, "EXPLAIN "+sqlStr, vars...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	for rows.Next() {
+		values := make([]sql.NullString, len(cols))
+		dest := make([]interface{}, len(cols))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return "", err
+		}
+		// postgres 只有 QUERY PLAN 一列，mysql 为多列表格
+		if len(cols) == 1 {
+			lines = append(lines, values[0].String)
+			continue
+		}
+		parts := make([]string, 0, len(cols))
+		for i, col := range cols {
+			if values[i].Valid {
+				parts = append(parts, fmt.Sprintf("%s=%s", col, values[i].String))
+			}
+		}
+		lines = append(lines, strings.Join(parts, " "))
+	}
+	return strings.Join(lines, "\n"), rows.Err()
+}
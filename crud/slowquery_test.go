@@ -0,0 +1,127 @@
+package crud_test
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/lazyfury/bowlutils/crud"
+	"github.com/lazyfury/bowlutils/db"
+	"github.com/lazyfury/bowlutils/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func observeLogs(t *testing.T) *observer.ObservedLogs {
+	t.Helper()
+	core, logs := observer.New(zapcore.DebugLevel)
+	prev := logger.Log
+	logger.Log = zap.New(core)
+	t.Cleanup(func() { logger.Log = prev })
+	return logs
+}
+
+func TestSlowQueryPlugin(t *testing.T) {
+	conn := newTestDB(t, &user{})
+	repo := crud.NewRepository(&user{}, conn)
+	seedUsers(t, repo, "alice", "bob")
+
+	// 阈值极小：所有查询都记录为慢查询，并带上 MapToSearch 的过滤字段
+	if err := conn.Use(crud.NewSlowQueryPlugin(crud.SlowQueryConfig{Threshold: time.Nanosecond})); err != nil {
+		t.Fatal(err)
+	}
+	logs := observeLogs(t)
+	var out []*user
+	search := repo.QueryParamsToSearch(map[string]string{"name__like": "a", "age__gte": "18", "age__sort": "desc", "unknown": "x"})
+	if err := repo.List(&out, search...); err != nil {
+		t.Fatal(err)
+	}
+	slow := logs.FilterMessage("slow query").All()
+	if len(slow) != 1 || slow[0].Level != zapcore.WarnLevel {
+		t.Fatalf("slow query logs = %+v", logs.All())
+	}
+	fields := slow[0].ContextMap()
+	if fields["table"] != "users" || fields["sql"] == "" {
+		t.Fatalf("fields = %+v", fields)
+	}
+	keys := toStrings(fields["filter_keys"])
+	sort.Strings(keys)
+	if want := []string{"age__gte", "age__sort", "name__like"}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("filter_keys = %v", keys)
+	}
+}
+
+func TestSlowQueryPlugin_Explain(t *testing.T) {
+	conn := newTestDB(t, &user{})
+	repo := crud.NewRepository(&user{}, conn)
+	seedUsers(t, repo, "alice")
+	if err := conn.Use(crud.NewSlowQueryPlugin(crud.SlowQueryConfig{Threshold: time.Hour, Explain: true})); err != nil {
+		t.Fatal(err)
+	}
+	logs := observeLogs(t)
+
+	// 低于阈值不记录
+	var out []*user
+	if err := repo.List(&out); err != nil {
+		t.Fatal(err)
+	}
+	if logs.Len() != 0 {
+		t.Fatalf("unexpected logs = %+v", logs.All())
+	}
+
+	// Explain() 强制输出；sqlite 不执行 EXPLAIN，不带 plan 与 explain_error
+	if err := repo.List(&out, crud.Explain()); err != nil {
+		t.Fatal(err)
+	}
+	entries := logs.FilterMessage("query explain").All()
+	if len(entries) != 1 || entries[0].Level != zapcore.InfoLevel {
+		t.Fatalf("explain logs = %+v", logs.All())
+	}
+	fields := entries[0].ContextMap()
+	if _, ok := fields["plan"]; ok {
+		t.Fatalf("sqlite should not explain: %+v", fields)
+	}
+	if _, ok := fields["explain_error"]; ok {
+		t.Fatalf("explain_error = %v", fields["explain_error"])
+	}
+}
+
+// 与 db.GormLogger 同时使用时慢查询只由插件记录一次
+func TestSlowQueryPlugin_GormLogger(t *testing.T) {
+	conn, err := db.Open(db.DBConfig{Driver: db.DriverSQLite, DSN: db.SQLiteMemory, LogLevel: "warn", SlowThreshold: time.Nanosecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close(conn) })
+	if err := conn.AutoMigrate(&user{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Use(crud.NewSlowQueryPlugin(crud.SlowQueryConfig{Threshold: time.Nanosecond})); err != nil {
+		t.Fatal(err)
+	}
+	logs := observeLogs(t)
+
+	var out []*user
+	if err := crud.NewRepository(&user{}, conn).List(&out); err != nil {
+		t.Fatal(err)
+	}
+	if logs.Len() != 1 || logs.FilterMessage("slow query").Len() != 1 {
+		t.Fatalf("logs = %+v", logs.All())
+	}
+}
+
+func toStrings(v interface{}) []string {
+	switch s := v.(type) {
+	case []string:
+		return append([]string(nil), s...)
+	case []interface{}:
+		out := make([]string, len(s))
+		for i, x := range s {
+			out[i], _ = x.(string)
+		}
+		return out
+	}
+	return nil
+}
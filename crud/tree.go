@@ -0,0 +1,338 @@
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/lazyfury/bowlutils/utils"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrTreeCycle 移动节点到自身或其子孙节点下
+	ErrTreeCycle = errors.New("crud: cannot move node under itself or its descendants")
+	// ErrTreePath 节点没有 path，通常是未通过 CreateNode 创建
+	ErrTreePath = errors.New("crud: tree node has no path, create it with CreateNode")
+)
+
+// TreeModel 可嵌入的树形模型，使用 parent_id + 物化路径（materialized path）保存层级关系
+// path 形如 /1/3/7/，包含从根节点到当前节点的所有 ID，depth 从 0 开始
+type TreeModel struct {
+	ParentID uint   `gorm:"index;default:0" json:"parent_id"`
+	Path     string `gorm:"size:767;index" json:"path"`
+	Depth    int    `gorm:"default:0" json:"depth"`
+}
+
+func (t *TreeModel) GetParentID() uint {
+	return t.ParentID
+}
+
+func (t *TreeModel) GetPath() string {
+	return t.Path
+}
+
+func (t *TreeModel) GetDepth() int {
+	return t.Depth
+}
+
+func (t *TreeModel) SetTreePath(parentID uint, path string, depth int) {
+	t.ParentID = parentID
+	t.Path = path
+	t.Depth = depth
+}
+
+// TreeNode 树形节点接口，嵌入 TreeModel 的模型自动实现
+type TreeNode interface {
+	Model
+	GetParentID() uint
+	GetPath() string
+	GetDepth() int
+	SetTreePath(parentID uint, path string, depth int)
+}
+
+// Tree 嵌套的树形结构，JSON 编码时节点字段与 children 平铺在同一层
+type Tree[T any] struct {
+	Node     T
+	Children []*Tree[T]
+}
+
+func (t *Tree[T]) MarshalJSON() ([]byte, error) {
+	m, err := utils.ToMap(t.Node)
+	if err != nil {
+		return nil, err
+	}
+	children := t.Children
+	if children == nil {
+		children = []*Tree[T]{}
+	}
+	m["children"] = children
+	return json.Marshal(m)
+}
+
+// TreeRepository 树形模型仓储，在 Repository 基础上提供层级查询
+type TreeRepository[T TreeNode] struct {
+	*Repository[T]
+}
+
+func NewTreeRepository[T TreeNode](model T, db *gorm.DB) *TreeRepository[T] {
+	return &TreeRepository[T]{
+		Repository: NewRepository(model, db),
+	}
+}
+
+// WithPolicy 返回使用 policy 的树形仓储副本
+func (r *TreeRepository[T]) WithPolicy(policy Policy[T]) *TreeRepository[T] {
+	return &TreeRepository[T]{Repository: r.Repository.WithPolicy(policy)}
+}
+
+// WithContext 返回绑定 ctx 的树形仓储副本，ctx 中有 db.WithTx 开启的事务时树操作加入该事务
+func (r *TreeRepository[T]) WithContext(ctx context.Context) *TreeRepository[T] {
+	return &TreeRepository[T]{Repository: r.Repository.WithContext(ctx)}
+}
+
+// WithScope 返回附加读取条件的树形仓储副本
+func (r *TreeRepository[T]) WithScope(scopes ...QueryFunc) *TreeRepository[T] {
+	return &TreeRepository[T]{Repository: r.Repository.WithScope(scopes...)}
+}
+
+// SeedUpsert 实现 Seedable，新节点通过 CreateNode 创建并计算 path，
+// 已有节点更新其他字段后，parent_id 变化时通过 Move 移动
+func (r *TreeRepository[T]) SeedUpsert(ctx context.Context, row map[string]interface{}, keys []string) (uint, error) {
+	repo := r.WithContext(ctx)
+	return repo.seedUpsert(row, keys, repo.CreateNode, func(existing T, changes map[string]interface{}) error {
+		parentID := existing.GetParentID()
+		if v, ok := changes["parent_id"]; ok {
+			model, err := repo.seedModel(map[string]interface{}{"parent_id": v})
+			if err != nil {
+				return err
+			}
+			parentID = model.GetParentID()
+			delete(changes, "parent_id")
+		}
+		if _, _, err := repo.patch(existing.GetID(), changes, nil); err != nil {
+			return err
+		}
+		if parentID != existing.GetParentID() {
+			return repo.Move(existing.GetID(), parentID)
+		}
+		return nil
+	})
+}
+
+// find node in tx，应用 policy 的读取条件；path 为空的节点返回 ErrTreePath，
+// 避免 LIKE path% 匹配整张表
+func (r *TreeRepository[T]) findNode(db *gorm.DB, id uint) (T, error) {
+	var node T
+	opts, err := r.scoped(nil)
+	if err != nil {
+		return node, err
+	}
+	db = db.Table(r.model.TableName()).Where("id = ?", id)
+	for _, opt := range opts {
+		db = opt(db)
+	}
+	if err := db.First(&node).Error; err != nil {
+		return node, err
+	}
+	if node.GetPath() == "" {
+		return node, ErrTreePath
+	}
+	return node, nil
+}
+
+// CreateNode 创建节点并根据 parent_id 计算 path 与 depth
+func (r *TreeRepository[T]) CreateNode(node T) error {
+	if err := r.authorize(ActionCreate, node); err != nil {
+		return err
+	}
+	return r.conn().Transaction(func(tx *gorm.DB) error {
+		parentPath, depth := "/", 0
+		if pid := node.GetParentID(); pid != 0 {
+			parent, err := r.findNode(tx, pid)
+			if err != nil {
+				return err
+			}
+			parentPath, depth = parent.GetPath(), parent.GetDepth()+1
+		}
+		if err := tx.Create(node).Error; err != nil {
+			return err
+		}
+		path := parentPath + strconv.FormatUint(uint64(node.GetID()), 10) + "/"
+		node.SetTreePath(node.GetParentID(), path, depth)
+		return tx.Table(r.model.TableName()).Where("id = ?", node.GetID()).UpdateColumns(map[string]interface{}{
+			"path":  path,
+			"depth": depth,
+		}).Error
+	})
+}
+
+// Children 直接子节点
+func (r *TreeRepository[T]) Children(id uint, opts ...QueryFunc) ([]T, error) {
+	var out []T
+	opts = append([]QueryFunc{func(db *gorm.DB) *gorm.DB {
+		return db.Where("parent_id = ?", id).Order("id asc")
+	}}, opts...)
+	if err := r.List(&out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Descendants 所有子孙节点（不含自身），按 depth 排序
+func (r *TreeRepository[T]) Descendants(id uint, opts ...QueryFunc) ([]T, error) {
+	node, err := r.findNode(r.conn(), id)
+	if err != nil {
+		return nil, err
+	}
+	var out []T
+	opts = append([]QueryFunc{func(db *gorm.DB) *gorm.DB {
+		return db.Where("path LIKE ?", node.GetPath()+"%").Where("id <> ?", id).Order("depth asc, id asc")
+	}}, opts...)
+	if err := r.List(&out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Ancestors 所有祖先节点（不含自身），从根节点开始排列
+func (r *TreeRepository[T]) Ancestors(id uint) ([]T, error) {
+	node, err := r.findNode(r.conn(), id)
+	if err != nil {
+		return nil, err
+	}
+	ids := parseTreePath(node.GetPath())
+	if len(ids) <= 1 {
+		return []T{}, nil
+	}
+	var out []T
+	if err := r.List(&out, func(db *gorm.DB) *gorm.DB {
+		return db.Where("id IN ?", ids[:len(ids)-1]).Order("depth asc")
+	}); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Move 将节点（连同子树）移动到 newParentID 下，newParentID 为 0 时移动为根节点
+// 节点及其子孙的 path、depth 在同一事务内更新
+func (r *TreeRepository[T]) Move(id uint, newParentID uint) error {
+	if id == newParentID {
+		return ErrTreeCycle
+	}
+	return r.conn().Transaction(func(tx *gorm.DB) error {
+		node, err := r.findNode(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := r.authorize(ActionUpdate, node); err != nil {
+			return err
+		}
+		parentPath, depth := "/", 0
+		if newParentID != 0 {
+			parent, err := r.findNode(tx, newParentID)
+			if err != nil {
+				return err
+			}
+			if strings.HasPrefix(parent.GetPath(), node.GetPath()) {
+				return ErrTreeCycle
+			}
+			if err := r.authorize(ActionUpdate, parent); err != nil {
+				return err
+			}
+			parentPath, depth = parent.GetPath(), parent.GetDepth()+1
+		}
+
+		oldPath := node.GetPath()
+		if r.policy != nil {
+			// 子树中的每个节点都会被修改，包括读取范围外的节点
+			var subtree []T
+			if err := tx.Table(r.model.TableName()).Where("path LIKE ?", oldPath+"%").Where("id <> ?", id).Find(&subtree).Error; err != nil {
+				return err
+			}
+			for _, n := range subtree {
+				if err := r.authorize(ActionUpdate, n); err != nil {
+					return err
+				}
+			}
+		}
+		newPath := parentPath + strconv.FormatUint(uint64(id), 10) + "/"
+		delta := depth - node.GetDepth()
+
+		// ID 在同一路径上唯一，oldPath 只会以前缀形式出现，REPLACE 在 MySQL/Postgres/SQLite 中均可用
+		if err := tx.Table(r.model.TableName()).Where("path LIKE ?", oldPath+"%").UpdateColumns(map[string]interface{}{
+			"path":  gorm.Expr("REPLACE(path, ?, ?)", oldPath, newPath),
+			"depth": gorm.Expr("depth + ?", delta),
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Table(r.model.TableName()).Where("id = ?", id).UpdateColumn("parent_id", newParentID).Error; err != nil {
+			return err
+		}
+		node.SetTreePath(newParentID, newPath, depth)
+		return nil
+	})
+}
+
+// Tree 以 rootID 为根构建嵌套结构
+func (r *TreeRepository[T]) Tree(rootID uint, opts ...QueryFunc) (*Tree[T], error) {
+	root, err := r.findNode(r.conn(), rootID)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := r.Descendants(rootID, opts...)
+	if err != nil {
+		return nil, err
+	}
+	forest := buildForest(append([]T{root}, nodes...))
+	if len(forest) == 0 {
+		return &Tree[T]{Node: root}, nil
+	}
+	return forest[0], nil
+}
+
+// Forest 构建整张表的树形结构，返回所有根节点
+func (r *TreeRepository[T]) Forest(opts ...QueryFunc) ([]*Tree[T], error) {
+	var nodes []T
+	opts = append([]QueryFunc{func(db *gorm.DB) *gorm.DB {
+		return db.Order("depth asc, id asc")
+	}}, opts...)
+	if err := r.List(&nodes, opts...); err != nil {
+		return nil, err
+	}
+	return buildForest(nodes), nil
+}
+
+// buildForest 按 depth 升序的节点列表组装为树，父节点不在列表中的节点视为根
+func buildForest[T TreeNode](nodes []T) []*Tree[T] {
+	var roots []*Tree[T]
+	index := make(map[uint]*Tree[T], len(nodes))
+	for _, n := range nodes {
+		t := &Tree[T]{Node: n, Children: []*Tree[T]{}}
+		index[n.GetID()] = t
+		if parent, ok := index[n.GetParentID()]; ok && n.GetParentID() != 0 {
+			parent.Children = append(parent.Children, t)
+			continue
+		}
+		roots = append(roots, t)
+	}
+	return roots
+}
+
+// parseTreePath /1/3/7/ => [1 3 7]
+func parseTreePath(path string) []uint {
+	var ids []uint
+	for _, s := range strings.Split(strings.Trim(path, "/"), "/") {
+		if s == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids
+}
@@ -0,0 +1,109 @@
+package crud_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lazyfury/bowlutils/crud"
+	"github.com/lazyfury/bowlutils/db"
+	"gorm.io/gorm"
+)
+
+type category struct {
+	crud.BaseModel
+	crud.TreeModel
+	Name string `json:"name"`
+}
+
+func (c *category) TableName() string { return "categories" }
+
+func TestTreeRepository(t *testing.T) {
+	conn := newTestDB(t, &category{})
+	repo := crud.NewTreeRepository(&category{}, conn)
+	create := func(name string, parent uint) *category {
+		c := &category{Name: name}
+		c.ParentID = parent
+		if err := repo.CreateNode(c); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	root := create("root", 0)
+	a := create("a", root.ID)
+	b := create("b", root.ID)
+	a1 := create("a1", a.ID)
+
+	children, err := repo.Children(root.ID)
+	if err != nil || len(children) != 2 {
+		t.Fatalf("Children = %v, %v", children, err)
+	}
+	desc, err := repo.Descendants(root.ID)
+	if err != nil || len(desc) != 3 {
+		t.Fatalf("Descendants = %v, %v", desc, err)
+	}
+	anc, err := repo.Ancestors(a1.ID)
+	if err != nil || len(anc) != 2 || anc[0].ID != root.ID || anc[1].ID != a.ID {
+		t.Fatalf("Ancestors = %v, %v", anc, err)
+	}
+
+	if err := repo.Move(a.ID, a1.ID); !errors.Is(err, crud.ErrTreeCycle) {
+		t.Fatalf("expected ErrTreeCycle, got %v", err)
+	}
+	if err := repo.Move(a.ID, b.ID); err != nil {
+		t.Fatal(err)
+	}
+	moved, _ := repo.FindByID(a1.ID)
+	if moved.Depth != 3 || moved.Path != "/1/3/2/4/" {
+		t.Fatalf("moved node = %+v", moved.TreeModel)
+	}
+
+	tree, err := repo.Tree(root.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree.Children) != 1 || tree.Children[0].Node.Name != "b" || tree.Children[0].Children[0].Children[0].Node.Name != "a1" {
+		t.Fatalf("unexpected tree")
+	}
+
+	// 通过 Create 插入的节点没有 path，树操作不能把 LIKE '%' 作用到整张表
+	orphan := &category{Name: "orphan"}
+	if err := repo.Create(orphan); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Descendants(orphan.ID); !errors.Is(err, crud.ErrTreePath) {
+		t.Fatalf("Descendants of orphan err = %v", err)
+	}
+	if err := repo.Move(orphan.ID, root.ID); !errors.Is(err, crud.ErrTreePath) {
+		t.Fatalf("Move orphan err = %v", err)
+	}
+	if err := repo.Move(b.ID, orphan.ID); !errors.Is(err, crud.ErrTreePath) {
+		t.Fatalf("Move under orphan err = %v", err)
+	}
+	if err := repo.CreateNode(&category{Name: "c", TreeModel: crud.TreeModel{ParentID: orphan.ID}}); !errors.Is(err, crud.ErrTreePath) {
+		t.Fatalf("CreateNode under orphan err = %v", err)
+	}
+	moved, _ = repo.FindByID(a1.ID)
+	if moved.Depth != 3 || moved.Path != "/1/3/2/4/" {
+		t.Fatalf("tree changed by failed moves: %+v", moved.TreeModel)
+	}
+
+	// WithScope / WithContext 返回的仍是树形仓储
+	scoped := repo.WithScope(func(db *gorm.DB) *gorm.DB { return db.Where("name <> ?", "b") })
+	if children, err := scoped.Children(root.ID); err != nil || len(children) != 0 {
+		t.Fatalf("scoped Children = %v, %v", children, err)
+	}
+	errRollback := errors.New("rollback")
+	err = db.WithTx(context.Background(), conn, func(ctx context.Context) error {
+		if err := repo.WithContext(ctx).CreateNode(&category{Name: "draft", TreeModel: crud.TreeModel{ParentID: root.ID}}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatal(err)
+	}
+	if children, err := repo.Children(root.ID); err != nil || len(children) != 1 {
+		t.Fatalf("CreateNode should roll back with the context transaction, Children = %v, %v", children, err)
+	}
+}
@@ -1,48 +1,254 @@
 package db
 
 import (
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	bowllogger "github.com/lazyfury/bowlutils/logger"
+	"github.com/lazyfury/bowlutils/viperinit"
+	"github.com/spf13/viper"
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
 )
 
 var (
 	DriverMySQL      = "mysql"
 	DriverPostgreSQL = "postgres"
+	DriverSQLite     = "sqlite" // 纯 Go 实现，无需 cgo，适合本地开发与测试
 )
 
 var (
 	DefaultDriver  = DriverPostgreSQL
-	DefaultDrivers = []string{DriverMySQL, DriverPostgreSQL}
+	DefaultDrivers = []string{DriverMySQL, DriverPostgreSQL, DriverSQLite}
+)
+
+// SQLiteMemory 内存数据库 DSN
+const SQLiteMemory = ":memory:"
+
+var memoryDBSeq atomic.Int64
+
+var (
+	DefaultRetryInterval    = time.Second
+	DefaultMaxRetryInterval = 30 * time.Second
+	DefaultSlowThreshold    = 200 * time.Millisecond
 )
 
+/*
+DBConfig 配置示例（yaml）:
+
+	database:
+	  driver: postgres
+	  dsn: host=localhost user=postgres dbname=app sslmode=disable
+	  max_open_conns: 50
+	  max_idle_conns: 10
+	  conn_max_lifetime: 30m
+	  conn_max_idle_time: 5m
+	  log_level: warn          # silent | error | warn | info
+	  slow_threshold: 200ms
+	  redact_columns: [password, token]
+	  table_prefix: app_
+	  singular_table: false
+	  connect_retries: 5       # 首次连接失败后的重试次数
+	  retry_interval: 1s       # 重试间隔，每次翻倍
+	  max_retry_interval: 30s
+	  replicas:                # 从库，读操作按 replica_policy 分发
+	    - host=replica1 user=postgres dbname=app sslmode=disable
+	  replica_policy: round_robin   # round_robin | random
+	  health_check_interval: 10s
+
+	cfg, err := db.LoadConfig(v, "database")
+	conn, err := db.Open(cfg)
+*/
 type DBConfig struct {
-	Driver string
-	DSN    string
+	Driver string `mapstructure:"driver"`
+	DSN    string `mapstructure:"dsn"`
+
+	// 连接池
+	MaxOpenConns    int           `mapstructure:"max_open_conns"`
+	MaxIdleConns    int           `mapstructure:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time"`
+
+	// 日志
+	LogLevel      string        `mapstructure:"log_level"`
+	SlowThreshold time.Duration `mapstructure:"slow_threshold"`
+	RedactColumns []string      `mapstructure:"redact_columns"` // 日志中脱敏的列，默认 DefaultRedactColumns
+
+	// 表命名
+	TablePrefix   string `mapstructure:"table_prefix"`
+	SingularTable bool   `mapstructure:"singular_table"`
+
+	// 首次连接重试
+	ConnectRetries   int           `mapstructure:"connect_retries"`
+	RetryInterval    time.Duration `mapstructure:"retry_interval"`
+	MaxRetryInterval time.Duration `mapstructure:"max_retry_interval"`
+
+	// 读写分离，配置从库后需使用 db.Close 关闭连接以停止健康检查
+	Replicas            []string      `mapstructure:"replicas"`
+	ReplicaPolicy       string        `mapstructure:"replica_policy"`
+	HealthCheckInterval time.Duration `mapstructure:"health_check_interval"`
 }
 
-func NewDB(driver string, dsn string, confs ...gorm.Option) *gorm.DB {
+// LoadConfig 从 viper 的 key 配置段读取 DBConfig
+func LoadConfig(v *viper.Viper, key string) (DBConfig, error) {
+	return viperinit.Section[DBConfig](v, key)
+}
+
+// Dialector 根据驱动名创建 gorm.Dialector
+func Dialector(driver string, dsn string) (gorm.Dialector, error) {
 	if driver == "" || driver == "auto" {
 		driver = DefaultDriver
 	}
+	switch driver {
+	case DriverMySQL:
+		return mysql.Open(dsn), nil
+	case DriverPostgreSQL:
+		return postgres.Open(dsn), nil
+	case DriverSQLite:
+		return sqlite.Open(sqliteDSN(dsn)), nil
+	default:
+		return nil, fmt.Errorf("db: unsupported driver: %s", driver)
+	}
+}
+
+// newDialector Open 创建 dialector 的方式，测试中可替换
+var newDialector = Dialector
+
+// sqliteDSN :memory: 在连接池中每个连接都是独立的数据库，
+// 改为以唯一名称共享缓存的内存数据库，同一个 *gorm.DB 的所有连接看到同一份数据
+func sqliteDSN(dsn string) string {
+	if dsn != SQLiteMemory {
+		return dsn
+	}
+	return fmt.Sprintf("file:bowlutils_memory_%d?mode=memory&cache=shared", memoryDBSeq.Add(1))
+}
+
+// ParseLogLevel silent | error | warn | info，空值为 error
+func ParseLogLevel(level string) (logger.LogLevel, error) {
+	switch strings.ToLower(level) {
+	case "silent":
+		return logger.Silent, nil
+	case "", "error":
+		return logger.Error, nil
+	case "warn", "warning":
+		return logger.Warn, nil
+	case "info":
+		return logger.Info, nil
+	default:
+		return logger.Error, fmt.Errorf("db: unknown log level: %s", level)
+	}
+}
+
+// Open 按配置打开数据库，失败时返回错误而不是 panic
+// 首次连接失败会按 ConnectRetries 指数退避重试，以等待启动较慢的数据库
+func Open(cfg DBConfig, confs ...gorm.Option) (*gorm.DB, error) {
+	if _, err := newDialector(cfg.Driver, cfg.DSN); err != nil {
+		return nil, err
+	}
+	level, err := ParseLogLevel(cfg.LogLevel)
+	if err != nil {
+		return nil, err
+	}
+	slow := cfg.SlowThreshold
+	if slow <= 0 {
+		slow = DefaultSlowThreshold
+	}
 
 	confs = append([]gorm.Option{
 		&gorm.Config{
-			Logger: logger.Default.LogMode(logger.Error),
+			Logger: NewGormLogger(GormLoggerConfig{
+				LogLevel:                  level,
+				SlowThreshold:             slow,
+				IgnoreRecordNotFoundError: true,
+				RedactColumns:             cfg.RedactColumns,
+			}),
+			NamingStrategy: schema.NamingStrategy{
+				TablePrefix:   cfg.TablePrefix,
+				SingularTable: cfg.SingularTable,
+			},
 		},
 	}, confs...)
 
+	interval := cfg.RetryInterval
+	if interval <= 0 {
+		interval = DefaultRetryInterval
+	}
+	maxInterval := cfg.MaxRetryInterval
+	if maxInterval <= 0 {
+		maxInterval = DefaultMaxRetryInterval
+	}
+
 	var DB *gorm.DB
-	var err error
-	switch driver {
-	case DriverMySQL:
-		DB, err = gorm.Open(mysql.Open(dsn), confs...)
-	case DriverPostgreSQL:
-		DB, err = gorm.Open(postgres.Open(dsn), confs...)
-	default:
-		panic("unsupported driver: " + driver)
+	for attempt := 0; ; attempt++ {
+		// dialector 持有连接状态，每次重试重新创建
+		dialector, _ := newDialector(cfg.Driver, cfg.DSN)
+		DB, err = gorm.Open(dialector, confs...)
+		if err == nil {
+			break
+		}
+		if attempt >= cfg.ConnectRetries {
+			return nil, fmt.Errorf("db: failed to connect database: %w", err)
+		}
+		bowllogger.Warnw("db: connect failed, retrying", "attempt", attempt+1, "retries", cfg.ConnectRetries, "wait", interval.String(), "error", err.Error())
+		time.Sleep(interval)
+		interval *= 2
+		if interval > maxInterval {
+			interval = maxInterval
+		}
+	}
+
+	sqlDB, err := DB.DB()
+	if err != nil {
+		if c, ok := DB.ConnPool.(io.Closer); ok {
+			c.Close()
+		}
+		return nil, err
+	}
+	if cfg.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
 	}
+	if cfg.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	}
+	if len(cfg.Replicas) > 0 {
+		if err := useReplicas(DB, cfg); err != nil {
+			sqlDB.Close()
+			return nil, err
+		}
+	}
+	return DB, nil
+}
+
+func NewDB(driver string, dsn string, confs ...gorm.Option) *gorm.DB {
+	dialector, err := Dialector(driver, dsn)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	confs = append([]gorm.Option{
+		&gorm.Config{
+			Logger: NewGormLogger(GormLoggerConfig{
+				LogLevel:                  logger.Error,
+				SlowThreshold:             DefaultSlowThreshold,
+				IgnoreRecordNotFoundError: true,
+			}),
+		},
+	}, confs...)
+
+	DB, err := gorm.Open(dialector, confs...)
 	if err != nil {
 		panic("failed to connect database: " + err.Error())
 	}
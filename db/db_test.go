@@ -0,0 +1,172 @@
+package db
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+func TestLoadConfig(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	err := v.ReadConfig(strings.NewReader(`
+database:
+  driver: sqlite
+  dsn: ":memory:"
+  max_open_conns: 7
+  conn_max_lifetime: 30m
+  conn_max_idle_time: 90s
+  slow_threshold: 200ms
+  redact_columns: [password, token]
+  connect_retries: 3
+  retry_interval: 1s
+  replicas:
+    - replica1
+    - replica2
+  health_check_interval: 10s
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(v, "database")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DBConfig{
+		Driver:              DriverSQLite,
+		DSN:                 SQLiteMemory,
+		MaxOpenConns:        7,
+		ConnMaxLifetime:     30 * time.Minute,
+		ConnMaxIdleTime:     90 * time.Second,
+		SlowThreshold:       200 * time.Millisecond,
+		RedactColumns:       []string{"password", "token"},
+		ConnectRetries:      3,
+		RetryInterval:       time.Second,
+		Replicas:            []string{"replica1", "replica2"},
+		HealthCheckInterval: 10 * time.Second,
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Fatalf("cfg = %+v", cfg)
+	}
+
+	if _, err := LoadConfig(v, "missing"); err == nil {
+		t.Fatal("expected error for missing section")
+	}
+}
+
+var errDialFailed = errors.New("dial failed")
+
+// flakyDialector 前 failures 次 Initialize 失败
+type flakyDialector struct {
+	gorm.Dialector
+	attempts *int
+	failures int
+}
+
+func (d flakyDialector) Initialize(db *gorm.DB) error {
+	*d.attempts++
+	if *d.attempts <= d.failures {
+		return errDialFailed
+	}
+	return d.Dialector.Initialize(db)
+}
+
+func injectFlakyDialector(t *testing.T, failures int) *int {
+	t.Helper()
+	attempts := new(int)
+	prev := newDialector
+	newDialector = func(driver, dsn string) (gorm.Dialector, error) {
+		d, err := Dialector(driver, dsn)
+		if err != nil {
+			return nil, err
+		}
+		return flakyDialector{Dialector: d, attempts: attempts, failures: failures}, nil
+	}
+	t.Cleanup(func() { newDialector = prev })
+	return attempts
+}
+
+func TestOpen_Retry(t *testing.T) {
+	cfg := DBConfig{Driver: DriverSQLite, DSN: SQLiteMemory, LogLevel: "silent", ConnectRetries: 2, RetryInterval: time.Millisecond}
+
+	attempts := injectFlakyDialector(t, 2)
+	conn, err := Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(conn)
+	if *attempts != 3 {
+		t.Fatalf("attempts = %d", *attempts)
+	}
+
+	attempts = injectFlakyDialector(t, 5)
+	if _, err := Open(cfg); !errors.Is(err, errDialFailed) {
+		t.Fatalf("err = %v", err)
+	}
+	if *attempts != 3 {
+		t.Fatalf("attempts = %d, want ConnectRetries+1", *attempts)
+	}
+}
+
+func TestOpen_Pool(t *testing.T) {
+	conn, err := Open(DBConfig{Driver: DriverSQLite, DSN: SQLiteMemory, LogLevel: "silent", MaxOpenConns: 7, ConnMaxLifetime: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(conn)
+	sqlDB, err := conn.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sqlDB.Stats().MaxOpenConnections; got != 7 {
+		t.Fatalf("MaxOpenConnections = %d", got)
+	}
+
+	if _, err := Open(DBConfig{Driver: "oracle"}); err == nil {
+		t.Fatal("expected unsupported driver error")
+	}
+	if _, err := Open(DBConfig{Driver: DriverSQLite, DSN: SQLiteMemory, LogLevel: "verbose"}); err == nil {
+		t.Fatal("expected unknown log level error")
+	}
+}
+
+// recordingDialector 记录 Initialize 后打开的连接池
+type recordingDialector struct {
+	gorm.Dialector
+	pools *[]gorm.ConnPool
+}
+
+func (d recordingDialector) Initialize(db *gorm.DB) error {
+	err := d.Dialector.Initialize(db)
+	*d.pools = append(*d.pools, db.ConnPool)
+	return err
+}
+
+func TestOpen_ClosesPoolOnReplicaError(t *testing.T) {
+	var pools []gorm.ConnPool
+	prev := newDialector
+	newDialector = func(driver, dsn string) (gorm.Dialector, error) {
+		d, err := Dialector(driver, dsn)
+		if err != nil {
+			return nil, err
+		}
+		return recordingDialector{Dialector: d, pools: &pools}, nil
+	}
+	t.Cleanup(func() { newDialector = prev })
+
+	cfg := DBConfig{Driver: DriverSQLite, DSN: SQLiteMemory, LogLevel: "silent", Replicas: []string{"file:" + t.TempDir() + "/missing/replica.db"}}
+	if _, err := Open(cfg); err == nil {
+		t.Fatal("expected replica error")
+	}
+	if len(pools) != 1 {
+		t.Fatalf("pools = %d", len(pools))
+	}
+	if ping(pools[0], time.Second) {
+		t.Fatal("primary pool should be closed when replicas fail")
+	}
+}
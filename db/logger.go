@@ -0,0 +1,195 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	bowllogger "github.com/lazyfury/bowlutils/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+)
+
+// RedactedValue 敏感参数在日志中的替代值
+const RedactedValue = "[REDACTED]"
+
+// DefaultRedactColumns 默认脱敏的列，列名包含其中任意一项即脱敏（不区分大小写）
+var DefaultRedactColumns = []string{"password", "passwd", "secret", "token", "api_key"}
+
+// GormLoggerConfig gorm 日志配置
+type GormLoggerConfig struct {
+	LogLevel logger.LogLevel
+	// SlowThreshold 超过该耗时以 warn 记录，0 表示不记录；
+	// 注册 crud.SlowQueryPlugin 后由插件记录慢查询（含过滤字段与 EXPLAIN），此项会被置为 0
+	SlowThreshold             time.Duration
+	IgnoreRecordNotFoundError bool
+	// RedactColumns 需要脱敏的列，为 nil 时使用 DefaultRedactColumns
+	RedactColumns []string
+	// ContextFields 从 context 中提取附加字段，为 nil 时使用 logger.ContextFields（request_id / trace_id）
+	ContextFields func(ctx context.Context) []interface{}
+}
+
+// GormLogger 将 gorm 日志输出到 logger.Log（zap）
+type GormLogger struct {
+	config GormLoggerConfig
+}
+
+var _ logger.Interface = (*GormLogger)(nil)
+
+func NewGormLogger(config GormLoggerConfig) *GormLogger {
+	if config.RedactColumns == nil {
+		config.RedactColumns = DefaultRedactColumns
+	}
+	if config.ContextFields == nil {
+		config.ContextFields = bowllogger.ContextFields
+	}
+	return &GormLogger{config: config}
+}
+
+func (l *GormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	c := *l
+	c.config.LogLevel = level
+	return &c
+}
+
+// WithSlowThreshold 返回使用新慢查询阈值的副本，0 表示不记录慢查询
+func (l *GormLogger) WithSlowThreshold(d time.Duration) *GormLogger {
+	c := *l
+	c.config.SlowThreshold = d
+	return &c
+}
+
+func (l *GormLogger) sugar(ctx context.Context) *zap.SugaredLogger {
+	// 调用位置由 gorm 的 FileWithLineNum 给出，不使用 zap 的 caller
+	s := bowllogger.Log.WithOptions(zap.WithCaller(false)).Sugar()
+	if fields := l.config.ContextFields(ctx); len(fields) > 0 {
+		s = s.With(fields...)
+	}
+	return s.With("source", utils.FileWithLineNum())
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.config.LogLevel >= logger.Info {
+		l.sugar(ctx).Infof(msg, data...)
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.config.LogLevel >= logger.Warn {
+		l.sugar(ctx).Warnf(msg, data...)
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.config.LogLevel >= logger.Error {
+		l.sugar(ctx).Errorf(msg, data...)
+	}
+}
+
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.config.LogLevel <= logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	kv := func() []interface{} {
+		sql, rows := fc()
+		return []interface{}{"elapsed", elapsed.String(), "rows", rows, "sql", sql}
+	}
+	switch {
+	case err != nil && l.config.LogLevel >= logger.Error && (!errors.Is(err, logger.ErrRecordNotFound) || !l.config.IgnoreRecordNotFoundError):
+		l.sugar(ctx).Errorw("sql error", append(kv(), "error", err.Error())...)
+	case l.config.SlowThreshold != 0 && elapsed > l.config.SlowThreshold && l.config.LogLevel >= logger.Warn:
+		l.sugar(ctx).Warnw(fmt.Sprintf("slow sql >= %v", l.config.SlowThreshold), kv()...)
+	case l.config.LogLevel == logger.Info:
+		l.sugar(ctx).Debugw("sql", kv()...)
+	}
+}
+
+// ParamsFilter 实现 gorm.ParamsFilter，替换敏感列对应的参数
+func (l *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if len(params) == 0 || len(l.config.RedactColumns) == 0 {
+		return sql, params
+	}
+	columns := placeholderColumns(sql, len(params))
+	var out []interface{}
+	for i, col := range columns {
+		if col == "" || !l.sensitive(col) {
+			continue
+		}
+		if out == nil {
+			out = append([]interface{}(nil), params...)
+		}
+		out[i] = RedactedValue
+	}
+	if out == nil {
+		return sql, params
+	}
+	return sql, out
+}
+
+func (l *GormLogger) sensitive(column string) bool {
+	column = strings.ToLower(column)
+	for _, c := range l.config.RedactColumns {
+		if strings.Contains(column, strings.ToLower(c)) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	placeholderRe = regexp.MustCompile(`\?|\$(\d+)`)
+	insertRe      = regexp.MustCompile("(?is)^\\s*INSERT\\s+INTO\\s+\\S+\\s*\\(([^)]*)\\)\\s*VALUES")
+	// 占位符前的 "列 操作符"，包括 IN (?,?, 中的后续占位符
+	comparisonRe = regexp.MustCompile("(?i)[`\"]?(\\w+)[`\"]?\\s*(?:=|<>|!=|<=|>=|<|>|\\bNOT\\s+I?LIKE|\\bI?LIKE|\\bNOT\\s+IN|\\bIN)\\s*(?:\\(\\s*(?:(?:\\?|\\$\\d+)\\s*,\\s*)*)?$")
+)
+
+const comparisonWindow = 256
+
+// placeholderColumns 尽力推断每个参数对应的列名，无法推断时为空字符串
+func placeholderColumns(sql string, n int) []string {
+	columns := make([]string, n)
+	var insertCols []string
+	valuesAt := -1
+	if m := insertRe.FindStringSubmatchIndex(sql); m != nil {
+		for _, c := range strings.Split(sql[m[2]:m[3]], ",") {
+			insertCols = append(insertCols, strings.Trim(strings.TrimSpace(c), "`\""))
+		}
+		valuesAt = m[1]
+	}
+
+	seq := 0
+	for _, loc := range placeholderRe.FindAllStringSubmatchIndex(sql, -1) {
+		idx := seq
+		if loc[2] >= 0 {
+			// postgres $n
+			num, err := strconv.Atoi(sql[loc[2]:loc[3]])
+			if err != nil {
+				continue
+			}
+			idx = num - 1
+		}
+		seq++
+		if idx < 0 || idx >= n {
+			continue
+		}
+		// 只检查占位符前的一小段，避免长 SQL 上的二次复杂度
+		start := loc[0] - comparisonWindow
+		if start < 0 {
+			start = 0
+		}
+		if m := comparisonRe.FindStringSubmatch(sql[start:loc[0]]); m != nil {
+			columns[idx] = m[1]
+			continue
+		}
+		if len(insertCols) > 0 && valuesAt >= 0 && loc[0] >= valuesAt {
+			columns[idx] = insertCols[idx%len(insertCols)]
+		}
+	}
+	return columns
+}
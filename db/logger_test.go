@@ -0,0 +1,157 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	bowllogger "github.com/lazyfury/bowlutils/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/gorm/logger"
+)
+
+func observeLogs(t *testing.T) *observer.ObservedLogs {
+	t.Helper()
+	core, logs := observer.New(zapcore.DebugLevel)
+	prev := bowllogger.Log
+	bowllogger.Log = zap.New(core)
+	t.Cleanup(func() { bowllogger.Log = prev })
+	return logs
+}
+
+func TestGormLogger_ParamsFilter(t *testing.T) {
+	l := NewGormLogger(GormLoggerConfig{})
+	ctx := context.Background()
+
+	tests := []struct {
+		sql    string
+		params []interface{}
+		want   []interface{}
+	}{
+		{
+			"SELECT * FROM `users` WHERE `name` = ? AND password_hash = ?",
+			[]interface{}{"alice", "h"},
+			[]interface{}{"alice", RedactedValue},
+		},
+		{
+			`UPDATE "users" SET "name"=$1,"api_key"=$2 WHERE "id" = $3`,
+			[]interface{}{"alice", "k", 1},
+			[]interface{}{"alice", RedactedValue, 1},
+		},
+		{
+			"INSERT INTO `users` (`name`,`password`) VALUES (?,?),(?,?)",
+			[]interface{}{"a", "p1", "b", "p2"},
+			[]interface{}{"a", RedactedValue, "b", RedactedValue},
+		},
+		{
+			"SELECT * FROM users WHERE token IN (?,?,?)",
+			[]interface{}{"t1", "t2", "t3"},
+			[]interface{}{RedactedValue, RedactedValue, RedactedValue},
+		},
+		{
+			"SELECT * FROM users WHERE id = ?",
+			[]interface{}{1},
+			[]interface{}{1},
+		},
+	}
+	for _, tt := range tests {
+		_, got := l.ParamsFilter(ctx, tt.sql, tt.params...)
+		if len(got) != len(tt.want) {
+			t.Fatalf("%s: got %v", tt.sql, got)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got %v, want %v", tt.sql, got, tt.want)
+				break
+			}
+		}
+		if tt.params[len(tt.params)-1] == RedactedValue {
+			t.Errorf("%s: input params must not be modified", tt.sql)
+		}
+	}
+}
+
+func TestGormLogger_Levels(t *testing.T) {
+	logs := observeLogs(t)
+	ctx := context.Background()
+	l := NewGormLogger(GormLoggerConfig{LogLevel: logger.Warn})
+
+	l.Info(ctx, "info %d", 1)
+	l.Warn(ctx, "warn %d", 2)
+	l.Error(ctx, "error %d", 3)
+	l.LogMode(logger.Silent).Error(ctx, "silent")
+	l.LogMode(logger.Info).Info(ctx, "verbose")
+
+	var got []string
+	for _, entry := range logs.All() {
+		got = append(got, entry.Level.String()+":"+entry.Message)
+	}
+	if want := "warn:warn 2,error:error 3,info:verbose"; strings.Join(got, ",") != want {
+		t.Fatalf("logs = %v", got)
+	}
+}
+
+func TestGormLogger_Trace(t *testing.T) {
+	fc := func() (string, int64) { return "SELECT 1", 1 }
+	slowBegin := time.Now().Add(-time.Second)
+
+	tests := []struct {
+		name      string
+		config    GormLoggerConfig
+		begin     time.Time
+		err       error
+		wantLevel zapcore.Level
+		wantMsg   string // 为空表示不输出
+	}{
+		{"error", GormLoggerConfig{LogLevel: logger.Error}, time.Now(), errors.New("boom"), zapcore.ErrorLevel, "sql error"},
+		{"record not found ignored", GormLoggerConfig{LogLevel: logger.Error, IgnoreRecordNotFoundError: true}, time.Now(), logger.ErrRecordNotFound, 0, ""},
+		{"slow", GormLoggerConfig{LogLevel: logger.Warn, SlowThreshold: 100 * time.Millisecond}, slowBegin, nil, zapcore.WarnLevel, "slow sql >= 100ms"},
+		{"slow below level", GormLoggerConfig{LogLevel: logger.Error, SlowThreshold: 100 * time.Millisecond}, slowBegin, nil, 0, ""},
+		{"slow disabled", GormLoggerConfig{LogLevel: logger.Warn}, slowBegin, nil, 0, ""},
+		{"fast", GormLoggerConfig{LogLevel: logger.Warn, SlowThreshold: time.Hour}, time.Now(), nil, 0, ""},
+		{"info", GormLoggerConfig{LogLevel: logger.Info, SlowThreshold: time.Hour}, time.Now(), nil, zapcore.DebugLevel, "sql"},
+		{"silent", GormLoggerConfig{LogLevel: logger.Silent}, time.Now(), errors.New("boom"), 0, ""},
+	}
+	for _, tt := range tests {
+		logs := observeLogs(t)
+		NewGormLogger(tt.config).Trace(context.Background(), tt.begin, fc, tt.err)
+		entries := logs.All()
+		if tt.wantMsg == "" {
+			if len(entries) != 0 {
+				t.Errorf("%s: unexpected logs %+v", tt.name, entries)
+			}
+			continue
+		}
+		if len(entries) != 1 || entries[0].Level != tt.wantLevel || entries[0].Message != tt.wantMsg {
+			t.Errorf("%s: logs = %+v", tt.name, entries)
+			continue
+		}
+		if fields := entries[0].ContextMap(); fields["sql"] != "SELECT 1" || fields["rows"] != int64(1) {
+			t.Errorf("%s: fields = %v", tt.name, fields)
+		}
+	}
+}
+
+func TestGormLogger_ContextFields(t *testing.T) {
+	logs := observeLogs(t)
+	ctx := bowllogger.WithTraceID(bowllogger.WithRequestID(context.Background(), "req-1"), "trace-1")
+	l := NewGormLogger(GormLoggerConfig{LogLevel: logger.Error})
+	l.Trace(ctx, time.Now(), func() (string, int64) { return "SELECT 1", 0 }, errors.New("boom"))
+	l.Error(context.Background(), "no ids")
+
+	entries := logs.All()
+	if len(entries) != 2 {
+		t.Fatalf("logs = %+v", entries)
+	}
+	fields := entries[0].ContextMap()
+	if fields["request_id"] != "req-1" || fields["trace_id"] != "trace-1" || fields["source"] == "" {
+		t.Fatalf("fields = %v", fields)
+	}
+	if _, ok := entries[1].ContextMap()["request_id"]; ok {
+		t.Fatal("request_id should be omitted without one in context")
+	}
+}
@@ -0,0 +1,272 @@
+package db
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/*
+Metrics 使用示例:
+
+	metrics := db.NewMetrics()
+	if err := metrics.Register("main", conn); err != nil {
+		return err
+	}
+	// 或由 Registry 在打开连接时自动注册
+	registry.WithMetrics(metrics)
+
+	http.Handle("/metrics/db", metrics.Handler())
+*/
+
+const metricsPluginName = "bowlutils:metrics"
+const settingMetricsStart = "bowlutils:metrics_start"
+
+// DefaultLatencyBuckets 查询耗时直方图的默认分桶（秒）
+var DefaultLatencyBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+type queryKey struct {
+	db        string
+	table     string
+	operation string
+}
+
+type queryStats struct {
+	count   uint64
+	errors  uint64
+	sum     float64
+	buckets []uint64 // 累计计数，与 Metrics.buckets 一一对应
+}
+
+// Metrics 收集连接池状态（sql.DBStats）与按表、操作统计的查询次数和耗时，
+// 以 Prometheus 文本格式输出，连接名作为 db 标签
+type Metrics struct {
+	buckets []float64
+	mu      sync.Mutex
+	pools   map[string]*sql.DB
+	queries map[queryKey]*queryStats
+}
+
+// NewMetrics 创建 Metrics，buckets 为空时使用 DefaultLatencyBuckets
+func NewMetrics(buckets ...float64) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &Metrics{
+		buckets: buckets,
+		pools:   make(map[string]*sql.DB),
+		queries: make(map[queryKey]*queryStats),
+	}
+}
+
+// Register 为连接注册查询回调，并采集其连接池状态
+func (m *Metrics) Register(name string, conn *gorm.DB) error {
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	if _, ok := m.pools[name]; ok {
+		m.mu.Unlock()
+		return fmt.Errorf("db: metrics for %q already registered", name)
+	}
+	m.pools[name] = sqlDB
+	m.mu.Unlock()
+	if err := conn.Use(&metricsPlugin{metrics: m, name: name}); err != nil {
+		// 注册失败时释放名称，以便之后重试
+		m.mu.Lock()
+		delete(m.pools, name)
+		m.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (m *Metrics) observe(key queryKey, elapsed time.Duration, failed bool) {
+	seconds := elapsed.Seconds()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats, ok := m.queries[key]
+	if !ok {
+		stats = &queryStats{buckets: make([]uint64, len(m.buckets))}
+		m.queries[key] = stats
+	}
+	stats.count++
+	stats.sum += seconds
+	if failed {
+		stats.errors++
+	}
+	for i, le := range m.buckets {
+		if seconds <= le {
+			stats.buckets[i]++
+		}
+	}
+}
+
+// Handler 输出 Prometheus 文本格式（text/plain; version=0.0.4）
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = m.Write(w)
+	})
+}
+
+// Write 将当前指标以 Prometheus 文本格式写入 w
+func (m *Metrics) Write(w io.Writer) error {
+	m.mu.Lock()
+	names := make([]string, 0, len(m.pools))
+	for name := range m.pools {
+		names = append(names, name)
+	}
+	pools := make(map[string]*sql.DB, len(m.pools))
+	for name, pool := range m.pools {
+		pools[name] = pool
+	}
+	keys := make([]queryKey, 0, len(m.queries))
+	queries := make(map[queryKey]queryStats, len(m.queries))
+	for key, stats := range m.queries {
+		keys = append(keys, key)
+		c := *stats
+		c.buckets = append([]uint64(nil), stats.buckets...)
+		queries[key] = c
+	}
+	m.mu.Unlock()
+
+	sort.Strings(names)
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.db != b.db {
+			return a.db < b.db
+		}
+		if a.table != b.table {
+			return a.table < b.table
+		}
+		return a.operation < b.operation
+	})
+
+	bw := bufio.NewWriter(w)
+	stats := make(map[string]sql.DBStats, len(names))
+	for _, name := range names {
+		stats[name] = pools[name].Stats()
+	}
+	poolMetrics := []struct {
+		name, help, kind string
+		value            func(s sql.DBStats) float64
+	}{
+		{"db_pool_max_open_connections", "Maximum number of open connections to the database.", "gauge", func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }},
+		{"db_pool_open_connections", "The number of established connections both in use and idle.", "gauge", func(s sql.DBStats) float64 { return float64(s.OpenConnections) }},
+		{"db_pool_in_use_connections", "The number of connections currently in use.", "gauge", func(s sql.DBStats) float64 { return float64(s.InUse) }},
+		{"db_pool_idle_connections", "The number of idle connections.", "gauge", func(s sql.DBStats) float64 { return float64(s.Idle) }},
+		{"db_pool_wait_count_total", "The total number of connections waited for.", "counter", func(s sql.DBStats) float64 { return float64(s.WaitCount) }},
+		{"db_pool_wait_duration_seconds_total", "The total time blocked waiting for a new connection.", "counter", func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }},
+		{"db_pool_max_idle_closed_total", "The total number of connections closed due to SetMaxIdleConns.", "counter", func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }},
+		{"db_pool_max_lifetime_closed_total", "The total number of connections closed due to SetConnMaxLifetime.", "counter", func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }},
+	}
+	for _, pm := range poolMetrics {
+		writeMetricHeader(bw, pm.name, pm.help, pm.kind)
+		for _, name := range names {
+			fmt.Fprintf(bw, "%s{db=%s} %s\n", pm.name, quoteLabel(name), formatFloat(pm.value(stats[name])))
+		}
+	}
+
+	writeMetricHeader(bw, "db_queries_total", "The total number of queries by table and operation.", "counter")
+	for _, key := range keys {
+		fmt.Fprintf(bw, "db_queries_total{%s} %d\n", key.labels(), queries[key].count)
+	}
+	writeMetricHeader(bw, "db_query_errors_total", "The total number of failed queries by table and operation.", "counter")
+	for _, key := range keys {
+		fmt.Fprintf(bw, "db_query_errors_total{%s} %d\n", key.labels(), queries[key].errors)
+	}
+	writeMetricHeader(bw, "db_query_duration_seconds", "Query latency by table and operation.", "histogram")
+	for _, key := range keys {
+		q := queries[key]
+		labels := key.labels()
+		for i, le := range m.buckets {
+			fmt.Fprintf(bw, "db_query_duration_seconds_bucket{%s,le=\"%s\"} %d\n", labels, formatFloat(le), q.buckets[i])
+		}
+		fmt.Fprintf(bw, "db_query_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, q.count)
+		fmt.Fprintf(bw, "db_query_duration_seconds_sum{%s} %s\n", labels, formatFloat(q.sum))
+		fmt.Fprintf(bw, "db_query_duration_seconds_count{%s} %d\n", labels, q.count)
+	}
+	return bw.Flush()
+}
+
+func (k queryKey) labels() string {
+	return "db=" + quoteLabel(k.db) + ",table=" + quoteLabel(k.table) + ",operation=" + quoteLabel(k.operation)
+}
+
+func writeMetricHeader(w io.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// quoteLabel 按 Prometheus 文本格式转义标签值
+func quoteLabel(v string) string {
+	return `"` + labelEscaper.Replace(v) + `"`
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// metricsPlugin 为单个连接注册统计回调
+type metricsPlugin struct {
+	metrics *Metrics
+	name    string
+}
+
+func (p *metricsPlugin) Name() string {
+	return metricsPluginName
+}
+
+func (p *metricsPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	for _, op := range []struct {
+		name          string
+		before, after func(name string, fn func(*gorm.DB)) error
+	}{
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+	} {
+		if err := op.before("bowlutils:metrics_before", p.before); err != nil {
+			return err
+		}
+		if err := op.after("bowlutils:metrics_after", p.after(op.name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *metricsPlugin) before(db *gorm.DB) {
+	db.InstanceSet(settingMetricsStart, time.Now())
+}
+
+func (p *metricsPlugin) after(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(settingMetricsStart)
+		if !ok {
+			return
+		}
+		failed := db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound)
+		key := queryKey{db: p.name, table: db.Statement.Table, operation: operation}
+		p.metrics.observe(key, time.Since(v.(time.Time)), failed)
+	}
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestMetrics(t *testing.T) {
+	metrics := NewMetrics(0.5, 1)
+	r := NewRegistry(map[string]DBConfig{
+		"main": {Driver: DriverSQLite, DSN: SQLiteMemory, LogLevel: "silent"},
+	}).WithMetrics(metrics)
+	defer r.Close()
+
+	conn := r.Must("main")
+	if err := conn.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)").Error; err != nil {
+		t.Fatal(err)
+	}
+	conn.Table("users").Create(map[string]interface{}{"name": "alice"})
+	var names []string
+	conn.Table("users").Pluck("name", &names)
+	conn.Table("missing").Pluck("name", &names)
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("content type = %s", rec.Header().Get("Content-Type"))
+	}
+	for _, want := range []string{
+		"# TYPE db_pool_open_connections gauge",
+		`db_pool_in_use_connections{db="main"} 0`,
+		`db_queries_total{db="main",table="users",operation="create"} 1`,
+		`db_queries_total{db="main",table="users",operation="query"} 1`,
+		`db_query_errors_total{db="main",table="missing",operation="query"} 1`,
+		`db_query_duration_seconds_bucket{db="main",table="users",operation="query",le="+Inf"} 1`,
+		`db_query_duration_seconds_count{db="main",table="users",operation="create"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %q in\n%s", want, body)
+		}
+	}
+}
+
+// Use 失败时不保留名称，之后可以用同一名称重新注册
+func TestMetrics_RegisterRetry(t *testing.T) {
+	open := func() *gorm.DB {
+		conn, err := Open(DBConfig{Driver: DriverSQLite, DSN: SQLiteMemory, LogLevel: "silent"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { Close(conn) })
+		return conn
+	}
+	used := open()
+	if err := NewMetrics().Register("other", used); err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := NewMetrics()
+	if err := metrics.Register("main", used); err == nil {
+		t.Fatal("expected plugin already registered error")
+	}
+	if err := metrics.Register("main", open()); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+}
@@ -0,0 +1,476 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+/*
+Migrator 使用示例:
+
+	//go:embed migrations/*.sql
+	var migrationsFS embed.FS
+
+	m := db.NewMigrator(conn)
+	// 文件命名：<版本>_<名称>.<up|down>[.<方言>].sql，版本都是数字时按数值排序
+	//   0001_create_users.up.sql
+	//   0001_create_users.down.sql
+	//   0002_add_index.up.postgres.sql / 0002_add_index.up.mysql.sql
+	if err := m.LoadFS(migrationsFS, "migrations"); err != nil {
+		return err
+	}
+	m.Add(&db.Migration{
+		ID:   "0003",
+		Name: "backfill_nickname",
+		Up: func(tx *gorm.DB) error {
+			return tx.Exec("UPDATE users SET nickname = name WHERE nickname IS NULL").Error
+		},
+	})
+	err := m.Up(ctx)
+
+MySQL 执行包含多条语句的 SQL 文件时 DSN 需要 multiStatements=true；
+MySQL 的 DDL 会隐式提交，失败时无法整体回滚。
+*/
+
+// DefaultMigrationTable 迁移记录表
+var DefaultMigrationTable = "schema_migrations"
+
+var (
+	ErrMigrationNoDown   = errors.New("db: migration has no down step")
+	ErrMigrationNoSQL    = errors.New("db: migration has no SQL for dialect")
+	ErrMigrationLocked   = errors.New("db: failed to acquire migration lock")
+	ErrMigrationNotFound = errors.New("db: applied migration not found")
+)
+
+// Migration 一个版本的迁移，Up/Down 函数优先于 SQL；SQL 以方言名为 key，"" 为通用版本
+type Migration struct {
+	ID      string
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+	UpSQL   map[string]string
+	DownSQL map[string]string
+}
+
+// MigrationStatus 迁移状态
+type MigrationStatus struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+type schemaMigration struct {
+	ID        string    `gorm:"primaryKey;size:191"`
+	Name      string    `gorm:"size:255"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// Migrator 版本化迁移执行器
+type Migrator struct {
+	db          *gorm.DB
+	table       string
+	lockTimeout time.Duration
+	migrations  map[string]*Migration
+}
+
+type MigratorOption func(*Migrator)
+
+// WithMigrationTable 设置迁移记录表名
+func WithMigrationTable(table string) MigratorOption {
+	return func(m *Migrator) {
+		m.table = table
+	}
+}
+
+// WithLockTimeout 设置获取迁移锁的超时，MySQL 传给 GET_LOCK，Postgres 在超时前重试 pg_try_advisory_lock
+func WithLockTimeout(d time.Duration) MigratorOption {
+	return func(m *Migrator) {
+		m.lockTimeout = d
+	}
+}
+
+func NewMigrator(db *gorm.DB, opts ...MigratorOption) *Migrator {
+	m := &Migrator{
+		db:          db,
+		table:       DefaultMigrationTable,
+		lockTimeout: time.Minute,
+		migrations:  make(map[string]*Migration),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Add 添加迁移，相同 ID 的迁移会合并 SQL 变体
+func (m *Migrator) Add(migrations ...*Migration) *Migrator {
+	for _, mg := range migrations {
+		existing, ok := m.migrations[mg.ID]
+		if !ok {
+			m.migrations[mg.ID] = mg
+			continue
+		}
+		if existing.Name == "" {
+			existing.Name = mg.Name
+		}
+		if mg.Up != nil {
+			existing.Up = mg.Up
+		}
+		if mg.Down != nil {
+			existing.Down = mg.Down
+		}
+		existing.UpSQL = mergeSQL(existing.UpSQL, mg.UpSQL)
+		existing.DownSQL = mergeSQL(existing.DownSQL, mg.DownSQL)
+	}
+	return m
+}
+
+func mergeSQL(dst, src map[string]string) map[string]string {
+	if dst == nil && len(src) > 0 {
+		dst = make(map[string]string, len(src))
+	}
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
+
+// LoadFS 从 fsys 的 dir 目录加载 SQL 迁移文件
+func (m *Migrator) LoadFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+		id, name, direction, dialect, err := parseMigrationFile(e.Name())
+		if err != nil {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return err
+		}
+		mg := &Migration{ID: id, Name: name}
+		if direction == "up" {
+			mg.UpSQL = map[string]string{dialect: string(data)}
+		} else {
+			mg.DownSQL = map[string]string{dialect: string(data)}
+		}
+		m.Add(mg)
+	}
+	return nil
+}
+
+// parseMigrationFile 0001_create_users.up.postgres.sql => 0001, create_users, up, postgres
+func parseMigrationFile(file string) (id, name, direction, dialect string, err error) {
+	parts := strings.Split(strings.TrimSuffix(file, ".sql"), ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return "", "", "", "", fmt.Errorf("db: invalid migration file name: %s", file)
+	}
+	direction = parts[1]
+	if direction != "up" && direction != "down" {
+		return "", "", "", "", fmt.Errorf("db: invalid migration direction in %s", file)
+	}
+	if len(parts) == 3 {
+		dialect = parts[2]
+	}
+	id, name, _ = strings.Cut(parts[0], "_")
+	if id == "" {
+		return "", "", "", "", fmt.Errorf("db: missing migration version in %s", file)
+	}
+	return id, name, direction, dialect, nil
+}
+
+// sorted 按 ID 排序的迁移
+func (m *Migrator) sorted() []*Migration {
+	list := make([]*Migration, 0, len(m.migrations))
+	for _, mg := range m.migrations {
+		list = append(list, mg)
+	}
+	sort.Slice(list, func(i, j int) bool { return migrationLess(list[i].ID, list[j].ID) })
+	return list
+}
+
+// migrationLess 比较迁移 ID，都是数字时按数值比较（"9" 在 "10" 之前），否则按字符串比较
+func migrationLess(a, b string) bool {
+	if isDigits(a) && isDigits(b) {
+		na, nb := strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+		if len(na) != len(nb) {
+			return len(na) < len(nb)
+		}
+		if na != nb {
+			return na < nb
+		}
+	}
+	return a < b
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Up 执行所有未应用的迁移
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(db *gorm.DB) error {
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+		for _, mg := range m.sorted() {
+			if _, ok := applied[mg.ID]; ok {
+				continue
+			}
+			if err := m.apply(db, mg, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Down 回滚最近应用的 steps 个迁移
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(db *gorm.DB) error {
+		return m.down(db, steps)
+	})
+}
+
+// Redo 回滚并重新执行最近一次迁移
+func (m *Migrator) Redo(ctx context.Context) error {
+	return m.withLock(ctx, func(db *gorm.DB) error {
+		records, err := m.records(db)
+		if err != nil || len(records) == 0 {
+			return err
+		}
+		last := records[len(records)-1]
+		mg, ok := m.migrations[last.ID]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrMigrationNotFound, last.ID)
+		}
+		if err := m.apply(db, mg, false); err != nil {
+			return err
+		}
+		return m.apply(db, mg, true)
+	})
+}
+
+// Status 返回所有迁移（包括已应用但代码中不存在的）的状态；只读取记录表，不加锁也不建表
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	db := m.primary(ctx)
+	applied := map[string]schemaMigration{}
+	if db.Migrator().HasTable(m.table) {
+		var err error
+		if applied, err = m.applied(db); err != nil {
+			return nil, err
+		}
+	}
+	var out []MigrationStatus
+	for _, mg := range m.sorted() {
+		st := MigrationStatus{ID: mg.ID, Name: mg.Name}
+		if r, ok := applied[mg.ID]; ok {
+			at := r.AppliedAt
+			st.Applied, st.AppliedAt = true, &at
+			delete(applied, mg.ID)
+		}
+		out = append(out, st)
+	}
+	for _, r := range applied {
+		at := r.AppliedAt
+		out = append(out, MigrationStatus{ID: r.ID, Name: r.Name, Applied: true, AppliedAt: &at})
+	}
+	sort.Slice(out, func(i, j int) bool { return migrationLess(out[i].ID, out[j].ID) })
+	return out, nil
+}
+
+func (m *Migrator) down(db *gorm.DB, steps int) error {
+	records, err := m.records(db)
+	if err != nil {
+		return err
+	}
+	for i := len(records) - 1; i >= 0 && steps > 0; i, steps = i-1, steps-1 {
+		mg, ok := m.migrations[records[i].ID]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrMigrationNotFound, records[i].ID)
+		}
+		if err := m.apply(db, mg, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply 在事务中执行迁移并更新记录表
+func (m *Migrator) apply(db *gorm.DB, mg *Migration, up bool) error {
+	fn, sqls := mg.Up, mg.UpSQL
+	if !up {
+		fn, sqls = mg.Down, mg.DownSQL
+	}
+	dialect := db.Dialector.Name()
+	if fn == nil {
+		sqlStr, ok := sqls[dialect]
+		if !ok {
+			sqlStr, ok = sqls[""]
+		}
+		if !ok {
+			if !up && len(sqls) == 0 {
+				return fmt.Errorf("%w: %s", ErrMigrationNoDown, mg.ID)
+			}
+			return fmt.Errorf("%w %s: %s", ErrMigrationNoSQL, dialect, mg.ID)
+		}
+		fn = func(tx *gorm.DB) error {
+			return tx.Exec(sqlStr).Error
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		if up {
+			return tx.Table(m.table).Create(&schemaMigration{ID: mg.ID, Name: mg.Name, AppliedAt: time.Now()}).Error
+		}
+		return tx.Table(m.table).Where("id = ?", mg.ID).Delete(&schemaMigration{}).Error
+	})
+	if err != nil {
+		direction := "up"
+		if !up {
+			direction = "down"
+		}
+		return fmt.Errorf("db: migration %s_%s %s: %w", mg.ID, mg.Name, direction, err)
+	}
+	return nil
+}
+
+func (m *Migrator) records(db *gorm.DB) ([]schemaMigration, error) {
+	var records []schemaMigration
+	if err := db.Table(m.table).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	sort.Slice(records, func(i, j int) bool { return migrationLess(records[i].ID, records[j].ID) })
+	return records, nil
+}
+
+func (m *Migrator) applied(db *gorm.DB) (map[string]schemaMigration, error) {
+	records, err := m.records(db)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[string]schemaMigration, len(records))
+	for _, r := range records {
+		applied[r.ID] = r
+	}
+	return applied, nil
+}
+
+// primary 绑定 ctx 的主库会话
+// 配置了从库时 dbresolver 会替换非事务语句的连接（*sql.Conn 不视为事务），
+// 标记为写操作使记录表的读取与 AutoMigrate 都走主库
+func (m *Migrator) primary(ctx context.Context) *gorm.DB {
+	return m.db.WithContext(ctx).Session(&gorm.Session{NewDB: true}).Clauses(dbresolver.Write).Session(&gorm.Session{})
+}
+
+// withLock 在独占连接上获取 advisory lock（Postgres pg_try_advisory_lock / MySQL GET_LOCK），
+// 确保多个实例同时启动时只有一个执行迁移；其他方言不加锁
+func (m *Migrator) withLock(ctx context.Context, fn func(db *gorm.DB) error) error {
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// 迁移事务在 conn 上开启
+	db := m.primary(ctx)
+	db.Statement.ConnPool = conn
+
+	lockKey := m.lockKey()
+	switch db.Dialector.Name() {
+	case DriverPostgreSQL:
+		// pg_advisory_lock 没有超时，会一直阻塞，改为在 lockTimeout 内轮询
+		if err := waitLock(ctx, m.lockTimeout, func() (bool, error) {
+			var got bool
+			err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", lockKey).Scan(&got)
+			return got, err
+		}); err != nil {
+			return err
+		}
+		defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+	case DriverMySQL:
+		var got *int
+		name := fmt.Sprintf("%s_%d", m.table, lockKey)
+		if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", name, int(m.lockTimeout.Seconds())).Scan(&got); err != nil {
+			return fmt.Errorf("%w: %v", ErrMigrationLocked, err)
+		}
+		if got == nil || *got != 1 {
+			return ErrMigrationLocked
+		}
+		defer conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", name)
+	}
+
+	if err := db.Table(m.table).AutoMigrate(&schemaMigration{}); err != nil {
+		return err
+	}
+	return fn(db)
+}
+
+func (m *Migrator) lockKey() int64 {
+	h := fnv.New64a()
+	h.Write([]byte("bowlutils:migrate:" + m.table))
+	return int64(h.Sum64() >> 1)
+}
+
+// lockRetryInterval 轮询 advisory lock 的间隔
+var lockRetryInterval = 500 * time.Millisecond
+
+// waitLock 重试 try 直到获取锁；timeout 内未获取返回 ErrMigrationLocked，ctx 取消时返回 ctx 的错误
+func waitLock(ctx context.Context, timeout time.Duration, try func() (bool, error)) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		got, err := try()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrMigrationLocked, err)
+		}
+		if got {
+			return nil
+		}
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return ErrMigrationLocked
+		}
+		if wait > lockRetryInterval {
+			wait = lockRetryInterval
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestParseMigrationFile(t *testing.T) {
+	tests := []struct {
+		file                         string
+		id, name, direction, dialect string
+		wantErr                      bool
+	}{
+		{"0001_create_users.up.sql", "0001", "create_users", "up", "", false},
+		{"0001_create_users.down.postgres.sql", "0001", "create_users", "down", "postgres", false},
+		{"0002.up.sql", "0002", "", "up", "", false},
+		{"0003_bad.sideways.sql", "", "", "", "", true},
+		{"0004_bad.sql", "", "", "", "", true},
+	}
+	for _, tt := range tests {
+		id, name, direction, dialect, err := parseMigrationFile(tt.file)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v", tt.file, err)
+			continue
+		}
+		if id != tt.id || name != tt.name || direction != tt.direction || dialect != tt.dialect {
+			t.Errorf("%s: got %s %s %s %s", tt.file, id, name, direction, dialect)
+		}
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	conn, err := Open(DBConfig{Driver: DriverSQLite, DSN: SQLiteMemory, LogLevel: "silent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	fsys := fstest.MapFS{
+		"migrations/0001_create_users.up.sql":       {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")},
+		"migrations/0001_create_users.down.sql":     {Data: []byte("DROP TABLE users")},
+		"migrations/0002_add_email.up.sqlite.sql":   {Data: []byte("ALTER TABLE users ADD COLUMN email TEXT")},
+		"migrations/0002_add_email.up.postgres.sql": {Data: []byte("ALTER TABLE users ADD COLUMN email VARCHAR(255)")},
+		"migrations/0002_add_email.down.sql":        {Data: []byte("ALTER TABLE users DROP COLUMN email")},
+		"migrations/README.md":                      {Data: []byte("ignored")},
+	}
+	m := NewMigrator(conn)
+	if err := m.LoadFS(fsys, "migrations"); err != nil {
+		t.Fatal(err)
+	}
+	m.Add(&Migration{
+		ID:   "0003",
+		Name: "seed",
+		Up: func(tx *gorm.DB) error {
+			return tx.Exec("INSERT INTO users (name, email) VALUES ('alice', 'a@example.com')").Error
+		},
+	})
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// 重复执行无副作用
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	conn.Table("users").Count(&count)
+	if count != 1 {
+		t.Fatalf("users count = %d", count)
+	}
+
+	status, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status) != 3 || !status[0].Applied || !status[2].Applied || status[1].Name != "add_email" {
+		t.Fatalf("status = %+v", status)
+	}
+
+	// 0003 没有 down
+	if err := m.Down(ctx, 1); !errors.Is(err, ErrMigrationNoDown) {
+		t.Fatalf("expected ErrMigrationNoDown, got %v", err)
+	}
+
+	m.Add(&Migration{ID: "0003", Down: func(tx *gorm.DB) error {
+		return tx.Exec("DELETE FROM users").Error
+	}})
+	if err := m.Redo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Down(ctx, 3); err != nil {
+		t.Fatal(err)
+	}
+	if conn.Migrator().HasTable("users") {
+		t.Fatal("users table should be dropped")
+	}
+	status, _ = m.Status(ctx)
+	for _, st := range status {
+		if st.Applied {
+			t.Fatalf("%s should not be applied", st.ID)
+		}
+	}
+}
+
+func TestMigrator_NumericOrder(t *testing.T) {
+	conn, err := Open(DBConfig{Driver: DriverSQLite, DSN: SQLiteMemory, LogLevel: "silent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(conn)
+	ctx := context.Background()
+
+	var ran []string
+	m := NewMigrator(conn)
+	for _, id := range []string{"10", "9", "2"} {
+		id := id
+		step := func(tx *gorm.DB) error {
+			ran = append(ran, id)
+			return nil
+		}
+		m.Add(&Migration{ID: id, Up: step, Down: step})
+	}
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(ran, ","); got != "2,9,10" {
+		t.Fatalf("up order = %s", got)
+	}
+	status, err := m.Status(ctx)
+	if err != nil || len(status) != 3 || status[2].ID != "10" {
+		t.Fatalf("status = %+v, %v", status, err)
+	}
+	ran = nil
+	if err := m.Down(ctx, 1); err != nil || strings.Join(ran, ",") != "10" {
+		t.Fatalf("down = %v, %v", ran, err)
+	}
+}
+
+func TestMigrator_Replicas(t *testing.T) {
+	// 落后的从库：记录表为空
+	replicaDSN := "file:bowlutils_migrate_replica?mode=memory&cache=shared"
+	replica := openSQLite(t, replicaDSN)
+	if err := replica.Table("schema_migrations").AutoMigrate(&schemaMigration{}); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := Open(DBConfig{Driver: DriverSQLite, DSN: SQLiteMemory, LogLevel: "silent", Replicas: []string{replicaDSN}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(conn)
+	ctx := context.Background()
+
+	m := NewMigrator(conn).Add(&Migration{
+		ID:   "0001",
+		Name: "create_items",
+		Up: func(tx *gorm.DB) error {
+			return tx.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY)").Error
+		},
+	})
+	for i := 0; i < 2; i++ {
+		// 第二次若从从库读取记录会重复执行 CREATE TABLE
+		if err := m.Up(ctx); err != nil {
+			t.Fatalf("Up #%d: %v", i+1, err)
+		}
+	}
+	status, err := m.Status(ctx)
+	if err != nil || len(status) != 1 || !status[0].Applied {
+		t.Fatalf("status = %+v, %v", status, err)
+	}
+}
+
+func TestMigrator_StatusReadOnly(t *testing.T) {
+	conn, err := Open(DBConfig{Driver: DriverSQLite, DSN: SQLiteMemory, LogLevel: "silent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(conn)
+
+	m := NewMigrator(conn).Add(&Migration{ID: "0001", Name: "noop", Up: func(tx *gorm.DB) error { return nil }})
+	status, err := m.Status(context.Background())
+	if err != nil || len(status) != 1 || status[0].Applied {
+		t.Fatalf("status = %+v, %v", status, err)
+	}
+	if conn.Migrator().HasTable(DefaultMigrationTable) {
+		t.Fatal("Status should not create the migration table")
+	}
+}
+
+func TestWaitLock(t *testing.T) {
+	interval := lockRetryInterval
+	lockRetryInterval = time.Millisecond
+	defer func() { lockRetryInterval = interval }()
+	ctx := context.Background()
+
+	tries := 0
+	if err := waitLock(ctx, time.Second, func() (bool, error) {
+		tries++
+		return tries == 3, nil
+	}); err != nil || tries != 3 {
+		t.Fatalf("tries = %d, %v", tries, err)
+	}
+
+	held := func() (bool, error) { return false, nil }
+	if err := waitLock(ctx, 5*time.Millisecond, held); !errors.Is(err, ErrMigrationLocked) {
+		t.Fatalf("expected ErrMigrationLocked, got %v", err)
+	}
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := waitLock(canceled, time.Second, held); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if err := waitLock(ctx, time.Second, func() (bool, error) { return false, errors.New("conn lost") }); !errors.Is(err, ErrMigrationLocked) {
+		t.Fatalf("expected ErrMigrationLocked, got %v", err)
+	}
+}
@@ -0,0 +1,223 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/lazyfury/bowlutils/viperinit"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+/*
+Registry 使用示例:
+
+	// config.yaml
+	databases:
+	  main:
+	    driver: postgres
+	    dsn: host=localhost user=postgres dbname=app sslmode=disable
+	  analytics:
+	    driver: mysql
+	    dsn: user:pass@tcp(localhost:3306)/analytics?parseTime=true
+
+	registry, err := db.LoadRegistry(v, "databases")
+	moduleManager.RegisterModule("databases", registry) // Stop 时关闭所有连接
+
+	mainDB := registry.Must("main")          // 首次使用时才建立连接
+	analytics, err := registry.Get("analytics")
+*/
+
+var (
+	// ErrUnknownDB 未配置的连接名
+	ErrUnknownDB = errors.New("db: unknown database")
+	// ErrRegistryClosed Registry 已关闭
+	ErrRegistryClosed = errors.New("db: registry closed")
+)
+
+type registryEntry struct {
+	mu  sync.Mutex
+	cfg DBConfig
+	db  *gorm.DB
+}
+
+// Registry 按名称管理多个数据库连接，首次 Get 时打开，实现 module.Module
+type Registry struct {
+	mu      sync.RWMutex
+	entries map[string]*registryEntry
+	closed  bool
+	metrics *Metrics
+}
+
+func NewRegistry(configs map[string]DBConfig) *Registry {
+	r := &Registry{entries: make(map[string]*registryEntry, len(configs))}
+	for name, cfg := range configs {
+		r.entries[name] = &registryEntry{cfg: cfg}
+	}
+	return r
+}
+
+// WithMetrics 连接打开时注册到 metrics，连接名作为 db 标签
+func (r *Registry) WithMetrics(metrics *Metrics) *Registry {
+	r.mu.Lock()
+	r.metrics = metrics
+	r.mu.Unlock()
+	return r
+}
+
+// LoadRegistry 从 viper 的 key 配置段（name -> DBConfig）创建 Registry
+func LoadRegistry(v *viper.Viper, key string) (*Registry, error) {
+	configs, err := viperinit.Section[map[string]DBConfig](v, key)
+	if err != nil {
+		return nil, err
+	}
+	return NewRegistry(configs), nil
+}
+
+// Names 已配置的连接名
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.entries))
+	for name := range r.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get 返回指定名称的连接，未打开时按配置打开；打开失败不会缓存，下次 Get 会重试
+func (r *Registry) Get(name string) (*gorm.DB, error) {
+	r.mu.RLock()
+	entry, ok := r.entries[name]
+	closed := r.closed
+	metrics := r.metrics
+	r.mu.RUnlock()
+	if closed {
+		return nil, ErrRegistryClosed
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDB, name)
+	}
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if entry.db != nil {
+		return entry.db, nil
+	}
+	// Close 先设置 closed 再逐个获取 entry.mu，持锁后再检查一次，
+	// 以免在 Close 处理过该连接后才打开，导致连接池泄漏
+	if r.isClosed() {
+		return nil, ErrRegistryClosed
+	}
+	conn, err := Open(entry.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("db: open %s: %w", name, err)
+	}
+	if metrics != nil {
+		if err := metrics.Register(name, conn); err != nil {
+			Close(conn)
+			return nil, err
+		}
+	}
+	entry.db = conn
+	return conn, nil
+}
+
+// Must 同 Get，失败时 panic
+func (r *Registry) Must(name string) *gorm.DB {
+	conn, err := r.Get(name)
+	if err != nil {
+		panic(err.Error())
+	}
+	return conn
+}
+
+// Start 实现 module.Module，连接在首次使用时打开
+func (r *Registry) Start(ctx context.Context, wg *sync.WaitGroup) error {
+	return nil
+}
+
+// Stop 实现 module.Module，关闭所有连接
+func (r *Registry) Stop() error {
+	return r.Close()
+}
+
+// Health 对所有已打开的连接执行 ping
+func (r *Registry) Health(ctx context.Context) error {
+	var errs []error
+	for _, name := range r.Names() {
+		conn := r.opened(name)
+		if conn == nil {
+			continue
+		}
+		sqlDB, err := conn.DB()
+		if err == nil {
+			err = sqlDB.PingContext(ctx)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close 关闭所有已打开的连接，之后 Get 返回 ErrRegistryClosed
+func (r *Registry) Close() error {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+
+	var errs []error
+	for _, name := range r.Names() {
+		r.mu.RLock()
+		entry := r.entries[name]
+		r.mu.RUnlock()
+
+		entry.mu.Lock()
+		if entry.db != nil {
+			if err := Close(entry.db); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+			entry.db = nil
+		}
+		entry.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Registry) isClosed() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.closed
+}
+
+func (r *Registry) opened(name string) *gorm.DB {
+	r.mu.RLock()
+	entry, ok := r.entries[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	return entry.db
+}
+
+// Close 关闭连接池，并停止 Open 注册的从库健康检查、关闭从库连接池
+func Close(conn *gorm.DB) error {
+	var replicaErr error
+	if p, ok := conn.Config.Plugins[ReplicaSetPluginName]; ok {
+		rs := p.(*ReplicaSet)
+		rs.Stop()
+		replicaErr = rs.closeReplicas()
+	}
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return err
+	}
+	return errors.Join(sqlDB.Close(), replicaErr)
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry(map[string]DBConfig{
+		"main":      {Driver: DriverSQLite, DSN: SQLiteMemory, LogLevel: "silent"},
+		"analytics": {Driver: DriverSQLite, DSN: SQLiteMemory, LogLevel: "silent"},
+	})
+	if names := r.Names(); len(names) != 2 || names[0] != "analytics" {
+		t.Fatalf("names = %v", names)
+	}
+	if _, err := r.Get("legacy"); !errors.Is(err, ErrUnknownDB) {
+		t.Fatalf("expected ErrUnknownDB, got %v", err)
+	}
+
+	main := r.Must("main")
+	if again := r.Must("main"); again != main {
+		t.Fatal("Get should return the same connection")
+	}
+	if r.opened("analytics") != nil {
+		t.Fatal("analytics should be opened lazily")
+	}
+	if err := r.Health(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Get("main"); !errors.Is(err, ErrRegistryClosed) {
+		t.Fatalf("expected ErrRegistryClosed, got %v", err)
+	}
+	sqlDB, _ := main.DB()
+	if err := sqlDB.Ping(); err == nil {
+		t.Fatal("connection should be closed")
+	}
+}
+
+// Get 与 Close 并发时，Get 返回的连接必须已被 Close 关闭，Registry 中不再缓存连接
+func TestRegistry_GetDuringClose(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		r := NewRegistry(map[string]DBConfig{"main": {Driver: DriverSQLite, DSN: SQLiteMemory, LogLevel: "silent"}})
+		var wg sync.WaitGroup
+		wg.Add(1)
+		var conn *gorm.DB
+		var getErr error
+		go func() {
+			defer wg.Done()
+			conn, getErr = r.Get("main")
+		}()
+		if err := r.Close(); err != nil {
+			t.Fatal(err)
+		}
+		wg.Wait()
+
+		if r.opened("main") != nil {
+			t.Fatal("registry should not cache a connection after Close")
+		}
+		if getErr != nil {
+			if !errors.Is(getErr, ErrRegistryClosed) {
+				t.Fatal(getErr)
+			}
+			continue
+		}
+		sqlDB, _ := conn.DB()
+		if err := sqlDB.Ping(); err == nil {
+			t.Fatal("connection returned before Close should be closed")
+		}
+	}
+}
@@ -0,0 +1,352 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lazyfury/bowlutils/logger"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+var (
+	ReplicaPolicyRoundRobin = "round_robin"
+	ReplicaPolicyRandom     = "random"
+)
+
+var DefaultHealthCheckInterval = 10 * time.Second
+
+// ReplicaSetPluginName ReplicaSet 注册到 gorm 的插件名，可通过 db.Config.Plugins 取回
+const ReplicaSetPluginName = "bowlutils:replicas"
+
+type primaryKey struct{}
+
+// WithPrimary 标记 ctx 中的读操作走主库（read-your-writes）
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// IsPrimary ctx 是否标记为走主库
+func IsPrimary(ctx context.Context) bool {
+	v, _ := ctx.Value(primaryKey{}).(bool)
+	return v
+}
+
+// UsePrimary 强制当前查询走主库，可直接作为 crud.QueryFunc 使用
+func UsePrimary(tx *gorm.DB) *gorm.DB {
+	return tx.Clauses(dbresolver.Write)
+}
+
+// ReplicaSet 从库负载均衡策略，健康检查失败的从库会被移出轮询，全部不可用时回退到主库；
+// 连接需通过 db.Close 关闭，以停止健康检查并关闭从库连接池；只关闭主库的 *sql.DB 不会停止健康检查
+type ReplicaSet struct {
+	primary  gorm.ConnPool
+	policy   dbresolver.Policy
+	interval time.Duration
+
+	labels []string // 按配置顺序的从库标识，用于日志
+
+	mu     sync.RWMutex
+	health map[gorm.ConnPool]bool
+	names  map[gorm.ConnPool]string
+
+	quit chan struct{}
+	done chan struct{} // healthLoop 退出后关闭
+	once sync.Once
+}
+
+func newReplicaSet(primary gorm.ConnPool, policy string, interval time.Duration) *ReplicaSet {
+	var p dbresolver.Policy = dbresolver.StrictRoundRobinPolicy()
+	if policy == ReplicaPolicyRandom {
+		p = dbresolver.RandomPolicy{}
+	}
+	if interval <= 0 {
+		interval = DefaultHealthCheckInterval
+	}
+	return &ReplicaSet{
+		primary:  primary,
+		policy:   p,
+		interval: interval,
+		health:   make(map[gorm.ConnPool]bool),
+		names:    make(map[gorm.ConnPool]string),
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// useReplicas 为 DB 注册从库读写分离
+func useReplicas(DB *gorm.DB, cfg DBConfig) error {
+	replicas := make([]gorm.Dialector, 0, len(cfg.Replicas))
+	labels := make([]string, 0, len(cfg.Replicas))
+	for i, dsn := range cfg.Replicas {
+		dialector, err := Dialector(cfg.Driver, dsn)
+		if err != nil {
+			return err
+		}
+		replicas = append(replicas, dialector)
+		labels = append(labels, replicaLabel(i, dsn))
+	}
+	rs := newReplicaSet(DB.ConnPool, cfg.ReplicaPolicy, cfg.HealthCheckInterval)
+	rs.labels = labels
+	resolver := dbresolver.Register(dbresolver.Config{
+		Replicas: replicas,
+		Policy:   rs,
+	})
+	if cfg.MaxOpenConns > 0 {
+		resolver.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		resolver.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		resolver.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		resolver.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	}
+	if err := DB.Use(resolver); err != nil {
+		closeResolverReplicas(resolver, DB.ConnPool)
+		return err
+	}
+	if err := DB.Use(rs); err != nil {
+		closeResolverReplicas(resolver, DB.ConnPool)
+		return err
+	}
+	return nil
+}
+
+// closeResolverReplicas 注册失败时关闭 dbresolver 已打开的从库连接池
+func closeResolverReplicas(resolver *dbresolver.DBResolver, primary gorm.ConnPool) {
+	primary = unwrapPool(primary)
+	_ = resolver.Call(func(pool gorm.ConnPool) error {
+		if c, ok := pool.(io.Closer); ok && pool != primary {
+			c.Close()
+		}
+		return nil
+	})
+}
+
+func (rs *ReplicaSet) Name() string {
+	return ReplicaSetPluginName
+}
+
+// Initialize 注册 WithPrimary 的回调，将 dbresolver 的从库加入健康检查并启动检查
+func (rs *ReplicaSet) Initialize(db *gorm.DB) error {
+	if resolver, ok := db.Config.Plugins["gorm:db_resolver"].(*dbresolver.DBResolver); ok {
+		primary := unwrapPool(rs.primary)
+		rs.mu.Lock()
+		i := 0
+		_ = resolver.Call(func(pool gorm.ConnPool) error {
+			if pool == primary {
+				return nil
+			}
+			if _, seen := rs.health[pool]; !seen {
+				rs.health[pool] = true
+				rs.names[pool] = rs.label(i)
+				i++
+			}
+			return nil
+		})
+		rs.mu.Unlock()
+	}
+
+	// dbresolver 只有一个从库时不会调用 Resolve，从库全部不可用时在这里切到主库
+	forcePrimary := func(db *gorm.DB) {
+		if (db.Statement.Context != nil && IsPrimary(db.Statement.Context)) || rs.allDown() {
+			dbresolver.Write.ModifyStatement(db.Statement)
+		}
+	}
+	// dbresolver 的回调注册为 Before("*")，这里同样使用 "*"；
+	// ModifyStatement 会立即重新执行 gorm:db_resolver，与两者的先后顺序无关
+	cb := db.Callback()
+	if err := cb.Query().Before("*").Register("bowlutils:force_primary", forcePrimary); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("*").Register("bowlutils:force_primary", forcePrimary); err != nil {
+		return err
+	}
+	if err := cb.Raw().Before("*").Register("bowlutils:force_primary", forcePrimary); err != nil {
+		return err
+	}
+	go rs.healthLoop()
+	return nil
+}
+
+// Resolve 实现 dbresolver.Policy，只在健康的从库中选择
+func (rs *ReplicaSet) Resolve(pools []gorm.ConnPool) gorm.ConnPool {
+	healthy := make([]gorm.ConnPool, 0, len(pools))
+	rs.mu.RLock()
+	unseen := false
+	for _, p := range pools {
+		ok, seen := rs.health[p]
+		if !seen {
+			unseen, ok = true, true
+		}
+		if ok {
+			healthy = append(healthy, p)
+		}
+	}
+	rs.mu.RUnlock()
+
+	// 未在 Initialize 中登记的从库默认健康，加入健康检查
+	if unseen {
+		rs.mu.Lock()
+		for _, p := range pools {
+			if _, seen := rs.health[p]; !seen {
+				rs.health[p] = true
+				rs.names[p] = rs.label(len(rs.names))
+			}
+		}
+		rs.mu.Unlock()
+	}
+
+	if len(healthy) == 0 {
+		return rs.primary
+	}
+	return rs.policy.Resolve(healthy)
+}
+
+// Healthy 返回健康从库数量与从库总数
+func (rs *ReplicaSet) Healthy() (healthy int, total int) {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	for _, ok := range rs.health {
+		if ok {
+			healthy++
+		}
+	}
+	return healthy, len(rs.health)
+}
+
+// allDown 从库均不可用
+func (rs *ReplicaSet) allDown() bool {
+	healthy, total := rs.Healthy()
+	return total > 0 && healthy == 0
+}
+
+// Stop 停止健康检查
+func (rs *ReplicaSet) Stop() {
+	rs.once.Do(func() {
+		close(rs.quit)
+	})
+}
+
+func (rs *ReplicaSet) healthLoop() {
+	defer close(rs.done)
+	ticker := time.NewTicker(rs.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			rs.check()
+		case <-rs.quit:
+			return
+		}
+	}
+}
+
+func (rs *ReplicaSet) check() {
+	rs.mu.RLock()
+	pools := make([]gorm.ConnPool, 0, len(rs.health))
+	for p := range rs.health {
+		pools = append(pools, p)
+	}
+	rs.mu.RUnlock()
+
+	for _, p := range pools {
+		ok := ping(p, rs.interval/2)
+		rs.mu.Lock()
+		if rs.health[p] != ok {
+			if ok {
+				logger.Infow("db: replica back in rotation", "replica", rs.names[p])
+			} else {
+				logger.Warnw("db: replica removed from rotation", "replica", rs.names[p])
+			}
+		}
+		rs.health[p] = ok
+		rs.mu.Unlock()
+	}
+}
+
+// closeReplicas 关闭 dbresolver 创建的从库连接池
+func (rs *ReplicaSet) closeReplicas() error {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	var errs []error
+	for p := range rs.health {
+		if c, ok := p.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func ping(pool gorm.ConnPool, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	switch p := pool.(type) {
+	case interface{ PingContext(context.Context) error }:
+		return p.PingContext(ctx) == nil
+	case interface{ Ping() error }:
+		return p.Ping() == nil
+	default:
+		return true
+	}
+}
+
+// label 第 i 个从库的标识
+func (rs *ReplicaSet) label(i int) string {
+	if i < len(rs.labels) {
+		return rs.labels[i]
+	}
+	return fmt.Sprintf("replicas[%d]", i)
+}
+
+// replicaLabel replicas[i] 加上 DSN 中的主机（不含账号密码）
+func replicaLabel(i int, dsn string) string {
+	label := fmt.Sprintf("replicas[%d]", i)
+	if host := dsnHost(dsn); host != "" {
+		label += " " + host
+	}
+	return label
+}
+
+// dsnHost 尽量从 URL、MySQL（user:pass@tcp(host:port)/db）与 key=value 形式的 DSN 中取出主机
+func dsnHost(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Host != "" {
+		return u.Host
+	}
+	if _, rest, ok := strings.Cut(dsn, "@"); ok {
+		if _, addr, ok := strings.Cut(rest, "("); ok {
+			addr, _, _ = strings.Cut(addr, ")")
+			return addr
+		}
+	}
+	var host, port string
+	for _, field := range strings.Fields(dsn) {
+		k, v, _ := strings.Cut(field, "=")
+		switch k {
+		case "host":
+			host = v
+		case "port":
+			port = v
+		}
+	}
+	if host != "" && port != "" {
+		return host + ":" + port
+	}
+	return host
+}
+
+func unwrapPool(pool gorm.ConnPool) gorm.ConnPool {
+	if p, ok := pool.(*gorm.PreparedStmtDB); ok {
+		return p.ConnPool
+	}
+	return pool
+}
@@ -0,0 +1,153 @@
+package db
+
+import (
+	"context"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+/*
+WithTx 使用示例:
+
+	// service 方法：多个仓储在同一事务中执行
+	err := db.WithTx(ctx, conn, func(ctx context.Context) error {
+		if err := orders.WithContext(ctx).Create(order); err != nil {
+			return err
+		}
+		// 嵌套调用使用 savepoint，内层失败只回滚到 savepoint
+		_ = db.WithTx(ctx, conn, func(ctx context.Context) error {
+			return audits.WithContext(ctx).Create(audit)
+		})
+		// 事务提交后才发送通知，回滚时不执行
+		db.AfterCommit(ctx, func() { bus.Publish("order.created", order) })
+		return stock.WithContext(ctx).Updates(item)
+	})
+
+	// 事务按数据库区分：analytics 的仓储不会加入 main 的事务，
+	// 在 main 的事务中对 analytics 调用 WithTx 会开启 analytics 自己的事务
+	err = db.WithTx(ctx, mainDB, func(ctx context.Context) error {
+		return db.WithTx(ctx, analyticsDB, func(ctx context.Context) error {
+			return events.WithContext(ctx).Create(event)
+		})
+	})
+*/
+
+type txKey struct{}
+
+// txState 一层事务（最外层为事务本身，内层为 savepoint）
+type txState struct {
+	tx    *gorm.DB
+	pool  gorm.ConnPool // 所属数据库的根连接池
+	outer *txState      // ctx 中先前的事务，可能属于其他数据库
+	mu    sync.Mutex
+	after []func()
+}
+
+// rootPool 标识 conn 所属的数据库：Session / WithContext / 事务都保留 Open 时的根连接池
+func rootPool(conn *gorm.DB) gorm.ConnPool {
+	return conn.Config.ConnPool
+}
+
+// lookupTx 从内到外查找 ctx 中属于 pool 的事务
+func lookupTx(ctx context.Context, pool gorm.ConnPool) *txState {
+	if ctx == nil {
+		return nil
+	}
+	state, _ := ctx.Value(txKey{}).(*txState)
+	for ; state != nil; state = state.outer {
+		if state.pool == pool {
+			return state
+		}
+	}
+	return nil
+}
+
+func (s *txState) addAfterCommit(fn func()) {
+	s.mu.Lock()
+	s.after = append(s.after, fn)
+	s.mu.Unlock()
+}
+
+func (s *txState) takeAfterCommit() []func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	after := s.after
+	s.after = nil
+	return after
+}
+
+// WithTx 在事务中执行 fn，事务通过 ctx 传递给 fn 内的仓储（Repository.WithContext）。
+// ctx 中已有同一数据库的事务时使用 savepoint 嵌套，fn 返回错误只回滚到该 savepoint；
+// 其他数据库的事务不受影响。最外层事务提交成功后按注册顺序执行 AfterCommit 回调
+func WithTx(ctx context.Context, conn *gorm.DB, fn func(ctx context.Context) error) error {
+	pool := rootPool(conn)
+	parent := lookupTx(ctx, pool)
+	base := conn.WithContext(ctx)
+	if parent != nil {
+		base = parent.tx.WithContext(ctx)
+	}
+
+	outer, _ := ctx.Value(txKey{}).(*txState)
+	state := &txState{pool: pool, outer: outer}
+	err := base.Transaction(func(tx *gorm.DB) error {
+		state.tx = tx
+		return fn(context.WithValue(ctx, txKey{}, state))
+	})
+	if err != nil {
+		return err
+	}
+
+	after := state.takeAfterCommit()
+	if parent != nil {
+		// savepoint 释放后回调交给外层，等待真正提交
+		for _, fn := range after {
+			parent.addAfterCommit(fn)
+		}
+		return nil
+	}
+	for _, fn := range after {
+		fn()
+	}
+	return nil
+}
+
+// TxFor 返回 ctx 中属于 conn 所在数据库的事务
+func TxFor(ctx context.Context, conn *gorm.DB) (*gorm.DB, bool) {
+	state := lookupTx(ctx, rootPool(conn))
+	if state == nil {
+		return nil, false
+	}
+	return state.tx.WithContext(ctx), true
+}
+
+// TxFrom 返回 ctx 中最内层的事务（不区分数据库，多数据库时使用 TxFor）
+func TxFrom(ctx context.Context) (*gorm.DB, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	state, ok := ctx.Value(txKey{}).(*txState)
+	if !ok {
+		return nil, false
+	}
+	return state.tx.WithContext(ctx), true
+}
+
+// Conn 返回 ctx 中属于 conn 所在数据库的事务，没有时返回绑定 ctx 的 conn
+func Conn(ctx context.Context, conn *gorm.DB) *gorm.DB {
+	if tx, ok := TxFor(ctx, conn); ok {
+		return tx
+	}
+	return conn.WithContext(ctx)
+}
+
+// AfterCommit 注册在最外层事务提交后执行的回调，事务或所在 savepoint 回滚时丢弃；
+// ctx 中没有事务时立即执行
+func AfterCommit(ctx context.Context, fn func()) {
+	state, ok := ctx.Value(txKey{}).(*txState)
+	if !ok {
+		fn()
+		return
+	}
+	state.addAfterCommit(fn)
+}
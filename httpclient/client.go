@@ -20,8 +20,14 @@ type Client struct {
 // RetryConfig 重试配置
 type RetryConfig struct {
 	MaxRetries int           // 最大重试次数
-	RetryDelay time.Duration // 重试延迟
+	RetryDelay time.Duration // 重试延迟，未设置 Backoff 时使用
 	RetryOn    []int         // 需要重试的状态码
+
+	Backoff        Backoff       // 退避策略，nil 时固定等待 RetryDelay
+	MaxDelay       time.Duration // 单次等待上限，0 表示不限制；Retry-After 超过上限时不再重试
+	IdempotentOnly bool          // 只重试幂等方法（或带 Idempotency-Key 的请求）
+	// ShouldRetry 自定义重试判断，替代 RetryOn 与默认的网络错误判断
+	ShouldRetry func(resp *http.Response, err error) bool
 }
 
 // New 创建新的HTTP客户端
@@ -132,52 +138,6 @@ func (c *Client) do(req *http.Request) (*Response, error) {
 	return newResponse(resp), nil
 }
 
-// doWithRetry 带重试的请求执行
-func (c *Client) doWithRetry(req *http.Request) (*http.Response, error) {
-	var resp *http.Response
-	var err error
-
-	for i := 0; i <= c.retryConfig.MaxRetries; i++ {
-		// 克隆请求以支持重试
-		reqClone := req.Clone(req.Context())
-
-		resp, err = c.httpClient.Do(reqClone)
-		if err != nil {
-			if i < c.retryConfig.MaxRetries {
-				time.Sleep(c.retryConfig.RetryDelay)
-				continue
-			}
-			return nil, err
-		}
-
-		// 检查是否需要重试
-		if c.shouldRetry(resp.StatusCode) && i < c.retryConfig.MaxRetries {
-			resp.Body.Close()
-			time.Sleep(c.retryConfig.RetryDelay)
-			continue
-		}
-
-		break
-	}
-
-	return resp, err
-}
-
-// shouldRetry 判断是否应该重试
-func (c *Client) shouldRetry(statusCode int) bool {
-	if c.retryConfig == nil || len(c.retryConfig.RetryOn) == 0 {
-		return false
-	}
-
-	for _, code := range c.retryConfig.RetryOn {
-		if code == statusCode {
-			return true
-		}
-	}
-
-	return false
-}
-
 // AddInterceptor 添加拦截器
 func (c *Client) AddInterceptor(interceptor Interceptor) {
 	c.interceptors = append(c.interceptors, interceptor)
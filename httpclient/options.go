@@ -52,14 +52,43 @@ func WithTransport(transport http.RoundTripper) Option {
 	}
 }
 
-// WithRetry 设置重试配置
+// WithRetry 设置重试次数、固定延迟与需要重试的状态码
 func WithRetry(maxRetries int, retryDelay time.Duration, retryOn ...int) Option {
 	return func(c *Client) {
-		c.retryConfig = &RetryConfig{
-			MaxRetries: maxRetries,
-			RetryDelay: retryDelay,
-			RetryOn:    retryOn,
-		}
+		cfg := c.retry()
+		cfg.MaxRetries = maxRetries
+		cfg.RetryDelay = retryDelay
+		cfg.RetryOn = retryOn
+	}
+}
+
+// WithRetryConfig 设置完整的重试配置
+func WithRetryConfig(config RetryConfig) Option {
+	return func(c *Client) {
+		c.retryConfig = &config
+	}
+}
+
+// WithBackoff 设置退避策略与单次等待上限
+func WithBackoff(backoff Backoff, maxDelay time.Duration) Option {
+	return func(c *Client) {
+		cfg := c.retry()
+		cfg.Backoff = backoff
+		cfg.MaxDelay = maxDelay
+	}
+}
+
+// WithIdempotentRetry 只重试幂等请求
+func WithIdempotentRetry() Option {
+	return func(c *Client) {
+		c.retry().IdempotentOnly = true
+	}
+}
+
+// WithShouldRetry 设置自定义重试判断
+func WithShouldRetry(fn func(resp *http.Response, err error) bool) Option {
+	return func(c *Client) {
+		c.retry().ShouldRetry = fn
 	}
 }
 
@@ -0,0 +1,206 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const maxDuration = time.Duration(math.MaxInt64)
+
+// Backoff 退避策略，attempt 从 0 开始，prev 为上一次的等待时间（首次为 0）
+type Backoff func(attempt int, prev time.Duration) time.Duration
+
+// ConstantBackoff 固定等待 d
+func ConstantBackoff(d time.Duration) Backoff {
+	return func(int, time.Duration) time.Duration {
+		return d
+	}
+}
+
+// ExponentialBackoff 等待 base * 2^attempt
+func ExponentialBackoff(base time.Duration) Backoff {
+	return func(attempt int, _ time.Duration) time.Duration {
+		if attempt >= 63 {
+			return maxDuration
+		}
+		d := base << attempt
+		if d>>attempt != base || d < 0 {
+			return maxDuration
+		}
+		return d
+	}
+}
+
+// DecorrelatedJitterBackoff 在 [base, prev*3) 之间随机等待，避免大量客户端同时重试；
+// 需配合 MaxDelay 限制增长
+func DecorrelatedJitterBackoff(base time.Duration) Backoff {
+	return func(_ int, prev time.Duration) time.Duration {
+		upper := maxDuration
+		if prev < maxDuration/3 {
+			upper = prev * 3
+		}
+		if upper <= base {
+			return base
+		}
+		return base + rand.N(upper-base)
+	}
+}
+
+// IsNetworkError 判断是否为可重试的网络错误（超时、连接被拒绝或重置、连接意外关闭），
+// context 取消不算在内
+func IsNetworkError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// IsIdempotent 判断请求是否幂等：GET/HEAD/OPTIONS/TRACE/PUT/DELETE，或带 Idempotency-Key 请求头
+func IsIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != "" || req.Header.Get("X-Idempotency-Key") != ""
+}
+
+// RetryAfter 解析 429/503 响应的 Retry-After（秒数或 HTTP 日期）
+func RetryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil || (resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable) {
+		return 0, false
+	}
+	v := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	if d := time.Until(t); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// retry 返回重试配置，不存在时创建
+func (c *Client) retry() *RetryConfig {
+	if c.retryConfig == nil {
+		c.retryConfig = &RetryConfig{}
+	}
+	return c.retryConfig
+}
+
+// shouldRetry 判断本次结果是否需要重试
+func (cfg *RetryConfig) shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if cfg.IdempotentOnly && !IsIdempotent(req) {
+		return false
+	}
+	if cfg.ShouldRetry != nil {
+		return cfg.ShouldRetry(resp, err)
+	}
+	if err != nil {
+		return IsNetworkError(err)
+	}
+	for _, code := range cfg.RetryOn {
+		if code == resp.StatusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// delay 第 attempt 次重试前的等待时间
+func (cfg *RetryConfig) delay(attempt int, prev time.Duration) time.Duration {
+	d := cfg.RetryDelay
+	if cfg.Backoff != nil {
+		d = cfg.Backoff(attempt, prev)
+	}
+	if cfg.MaxDelay > 0 && d > cfg.MaxDelay {
+		d = cfg.MaxDelay
+	}
+	return d
+}
+
+// doWithRetry 带重试的请求执行，等待期间 context 取消时立即返回
+func (c *Client) doWithRetry(req *http.Request) (*http.Response, error) {
+	cfg := c.retryConfig
+	ctx := req.Context()
+	// 请求体只能读取一次，无法重建时不重试
+	rewindable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+	var backoff time.Duration
+	for attempt := 0; ; attempt++ {
+		reqClone := req.Clone(ctx)
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			reqClone.Body = body
+		}
+
+		resp, err := c.httpClient.Do(reqClone)
+		if attempt >= cfg.MaxRetries || !rewindable || ctx.Err() != nil || !cfg.shouldRetry(req, resp, err) {
+			return resp, err
+		}
+
+		backoff = cfg.delay(attempt, backoff)
+		wait := backoff
+		if after, ok := RetryAfter(resp); ok {
+			if cfg.MaxDelay > 0 && after > cfg.MaxDelay {
+				return resp, nil
+			}
+			wait = after
+		}
+		if resp != nil {
+			// 读完剩余内容以便复用连接
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+			resp.Body.Close()
+		}
+
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
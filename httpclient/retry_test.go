@@ -0,0 +1,160 @@
+package httpclient_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lazyfury/bowlutils/httpclient"
+)
+
+func TestRetry_RetryAfterAndBody(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "payload" {
+			t.Errorf("attempt %d body = %q", calls.Load()+1, body)
+		}
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	client := httpclient.New(
+		httpclient.WithBaseURL(srv.URL),
+		httpclient.WithRetry(3, time.Hour, http.StatusServiceUnavailable),
+	)
+	resp, err := client.Put("/").Body(strings.NewReader("payload")).Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Close()
+	// Retry-After: 0 覆盖一小时的 RetryDelay
+	if resp.StatusCode != http.StatusOK || calls.Load() != 2 {
+		t.Fatalf("status = %d, calls = %d", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestRetry_Policies(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path == "/limited" {
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	client := httpclient.New(
+		httpclient.WithBaseURL(srv.URL),
+		httpclient.WithRetry(2, 0, http.StatusInternalServerError, http.StatusTooManyRequests),
+		httpclient.WithBackoff(httpclient.ExponentialBackoff(time.Millisecond), time.Second),
+		httpclient.WithIdempotentRetry(),
+	)
+
+	// 非幂等请求不重试
+	resp, err := client.Post("/").Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Close()
+	if calls.Swap(0) != 1 {
+		t.Fatal("POST should not be retried")
+	}
+
+	resp, err = client.Post("/").Header("Idempotency-Key", "k1").Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Close()
+	if n := calls.Swap(0); n != 3 {
+		t.Fatalf("POST with Idempotency-Key calls = %d", n)
+	}
+
+	// Retry-After 超过 MaxDelay 时直接返回
+	resp, err = client.Get("/limited").Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || calls.Swap(0) != 1 {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+
+	// 自定义判断
+	client = httpclient.New(
+		httpclient.WithBaseURL(srv.URL),
+		httpclient.WithRetry(2, 0),
+		httpclient.WithShouldRetry(func(resp *http.Response, err error) bool {
+			return resp != nil && resp.StatusCode >= 500
+		}),
+	)
+	resp, err = client.Get("/").Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Close()
+	if n := calls.Swap(0); n != 3 {
+		t.Fatalf("ShouldRetry calls = %d", n)
+	}
+}
+
+func TestRetry_ContextCancel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	client := httpclient.New(httpclient.WithRetry(5, time.Hour, http.StatusBadGateway))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := client.Get(srv.URL).Context(ctx).Do()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("wait ignored context")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	exp := httpclient.ExponentialBackoff(100 * time.Millisecond)
+	for attempt, want := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond} {
+		if got := exp(attempt, 0); got != want {
+			t.Fatalf("exponential(%d) = %v", attempt, got)
+		}
+	}
+	if exp(100, 0) <= 0 {
+		t.Fatal("exponential overflow")
+	}
+
+	jitter := httpclient.DecorrelatedJitterBackoff(10 * time.Millisecond)
+	prev := time.Duration(0)
+	for i := 0; i < 50; i++ {
+		d := jitter(i, prev)
+		if d < 10*time.Millisecond || (prev > 0 && d >= prev*3 && d != 10*time.Millisecond) {
+			t.Fatalf("jitter = %v (prev %v)", d, prev)
+		}
+		prev = d
+	}
+
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+	resp.Header.Set("Retry-After", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	if d, ok := httpclient.RetryAfter(resp); !ok || d <= 50*time.Second || d > time.Minute {
+		t.Fatalf("RetryAfter = %v, %v", d, ok)
+	}
+}
@@ -0,0 +1,46 @@
+package logger
+
+import "context"
+
+type requestIDKey struct{}
+type traceIDKey struct{}
+
+// WithRequestID 将请求 ID 放入 context
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID 从 context 中取出请求 ID
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithTraceID 将链路追踪 ID 放入 context
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, id)
+}
+
+// TraceID 从 context 中取出链路追踪 ID
+func TraceID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(traceIDKey{}).(string)
+	return id
+}
+
+// ContextFields 返回 context 中的 request_id / trace_id，用于 *w 系列日志函数
+func ContextFields(ctx context.Context) []interface{} {
+	var kv []interface{}
+	if id := RequestID(ctx); id != "" {
+		kv = append(kv, "request_id", id)
+	}
+	if id := TraceID(ctx); id != "" {
+		kv = append(kv, "trace_id", id)
+	}
+	return kv
+}
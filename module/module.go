@@ -11,6 +11,11 @@ type Module interface {
 	Stop() error
 }
 
+// HealthChecker 可选接口，模块实现后可由 ModuleManager.Health 检查健康状态
+type HealthChecker interface {
+	Health(ctx context.Context) error
+}
+
 // ModuleManager 模块管理器，用于管理所有模块的生命周期
 type ModuleManager struct {
 	modules map[string]Module
@@ -47,3 +52,18 @@ func (m *ModuleManager) StopAll() error {
 	}
 	return nil
 }
+
+// Health 检查所有实现 HealthChecker 的模块，返回不健康模块的错误（按模块名）
+func (m *ModuleManager) Health(ctx context.Context) map[string]error {
+	unhealthy := make(map[string]error)
+	for name, module := range m.modules {
+		hc, ok := module.(HealthChecker)
+		if !ok {
+			continue
+		}
+		if err := hc.Health(ctx); err != nil {
+			unhealthy[name] = err
+		}
+	}
+	return unhealthy
+}
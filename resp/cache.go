@@ -0,0 +1,264 @@
+package resp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+HTTP 缓存使用示例:
+
+	// 由编码后的响应体计算 ETag，If-None-Match 命中时返回 304
+	resp.OkWithRequest(w, r, product, resp.WithETag[Product]())
+
+	// 调用方提供版本（如 updated_at），命中时无需编码响应体
+	resp.OkWithRequest(w, r, product,
+		resp.WithVersion[Product](strconv.FormatInt(product.UpdatedAt.UnixNano(), 36)),
+		resp.WithCacheControl[Product](resp.CacheControl{Private: true, MaxAge: time.Minute}),
+		resp.WithVary[Product]("Authorization"),
+	)
+
+	// 不经过 resp 的 handler
+	resp.SetCacheControl(w, resp.CacheNoStore)
+	resp.AddVary(w, "Accept-Encoding")
+*/
+
+type etagMode int
+
+const (
+	etagNone etagMode = iota
+	etagStrong
+	etagWeak
+)
+
+// CacheControl Cache-Control 响应头，零值不输出
+type CacheControl struct {
+	Public               bool
+	Private              bool
+	NoCache              bool
+	NoStore              bool
+	MustRevalidate       bool
+	Immutable            bool
+	MaxAge               time.Duration
+	SMaxAge              time.Duration
+	StaleWhileRevalidate time.Duration
+}
+
+var (
+	// CacheNoStore 禁止缓存
+	CacheNoStore = CacheControl{NoStore: true}
+	// CacheRevalidate 允许缓存，但每次使用前需用 ETag 重新验证
+	CacheRevalidate = CacheControl{NoCache: true}
+)
+
+func (c CacheControl) String() string {
+	var parts []string
+	flag := func(ok bool, name string) {
+		if ok {
+			parts = append(parts, name)
+		}
+	}
+	seconds := func(d time.Duration, name string) {
+		if d > 0 {
+			parts = append(parts, name+"="+strconv.FormatInt(int64(d/time.Second), 10))
+		}
+	}
+	flag(c.Public, "public")
+	flag(c.Private, "private")
+	flag(c.NoCache, "no-cache")
+	flag(c.NoStore, "no-store")
+	seconds(c.MaxAge, "max-age")
+	seconds(c.SMaxAge, "s-maxage")
+	seconds(c.StaleWhileRevalidate, "stale-while-revalidate")
+	flag(c.MustRevalidate, "must-revalidate")
+	flag(c.Immutable, "immutable")
+	return strings.Join(parts, ", ")
+}
+
+// SetCacheControl 设置 Cache-Control，c 为零值时不修改
+func SetCacheControl(w http.ResponseWriter, c CacheControl) {
+	if v := c.String(); v != "" {
+		w.Header().Set("Cache-Control", v)
+	}
+}
+
+// AddVary 追加 Vary 响应头，已存在的字段（不区分大小写）不重复添加
+func AddVary(w http.ResponseWriter, fields ...string) {
+	h := w.Header()
+	existing := make(map[string]bool)
+	for _, v := range h.Values("Vary") {
+		for _, f := range strings.Split(v, ",") {
+			existing[strings.ToLower(strings.TrimSpace(f))] = true
+		}
+	}
+	for _, f := range fields {
+		key := strings.ToLower(strings.TrimSpace(f))
+		if key == "" || existing[key] {
+			continue
+		}
+		existing[key] = true
+		h.Add("Vary", f)
+	}
+}
+
+// WithETag 由协商的格式与编码后的响应体计算强 ETag；
+// 信封有附加字段（timestamp、request_id 等）时响应体每次请求都不同，
+// 改为由字段名与 code、msg、data 计算弱 ETag
+func WithETag[T any]() option[T] {
+	return func(r *resp[T]) {
+		r.etag = etagStrong
+	}
+}
+
+// WithWeakETag 输出弱 ETag（W/"..."），适用于语义相同但字节可能不同的响应
+func WithWeakETag[T any]() option[T] {
+	return func(r *resp[T]) {
+		r.etag = etagWeak
+	}
+}
+
+// WithVersion 以调用方提供的版本作为 ETag，命中 If-None-Match 时不编码响应体；
+// 版本需覆盖所有影响响应体的因素（包括协商的格式与语言）
+func WithVersion[T any](version string) option[T] {
+	return func(r *resp[T]) {
+		r.version = version
+		if r.etag == etagNone {
+			r.etag = etagStrong
+		}
+	}
+}
+
+// WithCacheControl 设置本次响应的 Cache-Control
+func WithCacheControl[T any](c CacheControl) option[T] {
+	return func(r *resp[T]) {
+		r.cacheControl = c
+	}
+}
+
+// WithVary 追加本次响应的 Vary
+func WithVary[T any](fields ...string) option[T] {
+	return func(r *resp[T]) {
+		r.vary = append(r.vary, fields...)
+	}
+}
+
+// quoteETag 未加引号的值补上引号，weak 时添加 W/ 前缀
+func quoteETag(tag string, weak bool) string {
+	if !strings.HasPrefix(tag, `"`) && !strings.HasPrefix(tag, `W/"`) {
+		tag = strconv.Quote(tag)
+	}
+	if weak && !strings.HasPrefix(tag, "W/") {
+		tag = "W/" + tag
+	}
+	return tag
+}
+
+// applyCache 写入 Cache-Control 与 Vary
+func (b *resp[T]) applyCache() {
+	SetCacheControl(b.w, b.cacheControl)
+	AddVary(b.w, b.vary...)
+}
+
+// notModified 请求为 GET/HEAD 且 If-None-Match 命中 tag 时写入 304
+func (b *resp[T]) notModified(tag string) bool {
+	if b.r == nil || (b.r.Method != http.MethodGet && b.r.Method != http.MethodHead) {
+		return false
+	}
+	inm := b.r.Header.Get("If-None-Match")
+	if inm == "" || !etagMatch(inm, tag) {
+		return false
+	}
+	b.w.Header().Del("Content-Type")
+	b.w.Header().Del("Content-Length")
+	b.w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// writeCached 输出带 ETag 的响应：有版本时直接比较，否则对编码后的响应体计算摘要，
+// 信封有附加字段时由 etagDigest 计算弱 ETag
+func (b *resp[T]) writeCached(mediaType string, enc Encoder, body any) {
+	weak := b.etag == etagWeak
+	if b.version != "" {
+		tag := quoteETag(b.version, weak)
+		b.w.Header().Set("ETag", tag)
+		if b.notModified(tag) {
+			return
+		}
+		b.w.Header().Set("Content-Type", mediaType)
+		b.w.WriteHeader(b.status)
+		if err := enc.Encode(b.w, body); err != nil {
+			http.Error(b.w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	envelope := b.currentEnvelope()
+	if len(envelope.Extra) > 0 {
+		digest, err := b.etagDigest(mediaType, envelope)
+		if err != nil {
+			http.Error(b.w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		tag := quoteETag(digest, true)
+		b.w.Header().Set("ETag", tag)
+		if b.notModified(tag) {
+			return
+		}
+	}
+	var buf bytes.Buffer
+	if err := enc.Encode(&buf, body); err != nil {
+		http.Error(b.w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(envelope.Extra) == 0 {
+		h := sha256.New()
+		h.Write([]byte(mediaType + "\n"))
+		h.Write(buf.Bytes())
+		tag := quoteETag(hex.EncodeToString(h.Sum(nil)[:16]), weak)
+		b.w.Header().Set("ETag", tag)
+		if b.notModified(tag) {
+			return
+		}
+	}
+	b.w.Header().Set("Content-Type", mediaType)
+	b.w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	b.w.WriteHeader(b.status)
+	_, _ = b.w.Write(buf.Bytes())
+}
+
+// etagDigest 对 mediaType、信封的字段名与 JSON 编码的 code、msg、data 计算摘要，不包括附加字段的值
+func (b *resp[T]) etagDigest(mediaType string, envelope *Envelope) (string, error) {
+	h := sha256.New()
+	keys := make([]string, 0, len(envelope.Extra))
+	for k := range envelope.Extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h.Write([]byte(mediaType + "\n"))
+	err := json.NewEncoder(h).Encode(struct {
+		Keys  []string `json:"keys"`
+		Extra []string `json:"extra"`
+		Code  int      `json:"code"`
+		Msg   string   `json:"msg"`
+		Data  T        `json:"data"`
+	}{
+		[]string{orDefault(envelope.CodeKey, "code"), orDefault(envelope.MsgKey, "msg"), orDefault(envelope.DataKey, "data")},
+		keys, b.code, b.msg, b.data,
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16]), nil
+}
+
+// OkWithRequest 绑定请求的成功响应，支持内容协商、消息翻译与 ETag/304
+func OkWithRequest[T any](w http.ResponseWriter, r *http.Request, data T, opts ...option[T]) {
+	Ok(w, data, append([]option[T]{WithRequest[T](r)}, opts...)...)
+}
@@ -0,0 +1,149 @@
+package resp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lazyfury/bowlutils/logger"
+)
+
+func TestOk_ETag(t *testing.T) {
+	r := httptest.NewRequest("GET", "/items/1", nil)
+	w := httptest.NewRecorder()
+	OkWithRequest(w, r, map[string]int{"id": 1}, WithETag[map[string]int]())
+	tag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || tag == "" || tag[0] != '"' {
+		t.Fatalf("status = %d, ETag = %q", w.Code, tag)
+	}
+	if got := w.Header().Get("Content-Length"); got != strconv.Itoa(w.Body.Len()) {
+		t.Fatalf("Content-Length = %s, body = %d bytes", got, w.Body.Len())
+	}
+	// 没有附加字段时由编码后的响应体计算
+	sum := sha256.Sum256(append([]byte("application/json\n"), w.Body.Bytes()...))
+	if want := `"` + hex.EncodeToString(sum[:16]) + `"`; tag != want {
+		t.Fatalf("ETag = %s, want %s", tag, want)
+	}
+
+	r = httptest.NewRequest("GET", "/items/1", nil)
+	r.Header.Set("If-None-Match", tag)
+	w = httptest.NewRecorder()
+	OkWithRequest(w, r, map[string]int{"id": 1}, WithETag[map[string]int]())
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body)
+	}
+	if w.Header().Get("ETag") != tag {
+		t.Fatalf("304 ETag = %q", w.Header().Get("ETag"))
+	}
+
+	// 内容变化后 ETag 不再匹配
+	w = httptest.NewRecorder()
+	OkWithRequest(w, r, map[string]int{"id": 2}, WithETag[map[string]int]())
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+
+	// 弱 ETag 与强 ETag 按弱比较匹配
+	w = httptest.NewRecorder()
+	OkWithRequest(w, r, map[string]int{"id": 1}, WithWeakETag[map[string]int]())
+	if w.Code != http.StatusNotModified || w.Header().Get("ETag") != "W/"+tag {
+		t.Fatalf("status = %d, ETag = %q", w.Code, w.Header().Get("ETag"))
+	}
+
+	// 非 GET 请求不返回 304
+	r = httptest.NewRequest("PUT", "/items/1", nil)
+	r.Header.Set("If-None-Match", tag)
+	w = httptest.NewRecorder()
+	OkWithRequest(w, r, map[string]int{"id": 1}, WithETag[map[string]int]())
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d", w.Code)
+	}
+}
+
+func TestOk_ETagExtra(t *testing.T) {
+	old := DefaultEnvelope
+	DefaultEnvelope = &Envelope{Extra: map[string]ExtraField{"timestamp": TimestampField, "request_id": RequestIDField}}
+	defer func() { DefaultEnvelope = old }()
+
+	send := func(requestID, accept, inm string, data int) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/items/1", nil)
+		r = r.WithContext(logger.WithRequestID(r.Context(), requestID))
+		r.Header.Set("Accept", accept)
+		r.Header.Set("If-None-Match", inm)
+		w := httptest.NewRecorder()
+		OkWithRequest(w, r, data, WithETag[int]())
+		return w
+	}
+
+	w := send("req-1", "application/json", "", 1)
+	tag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "req-1") {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body)
+	}
+	// 响应体每次不同，只能是弱 ETag
+	if !strings.HasPrefix(tag, `W/"`) {
+		t.Fatalf("ETag = %q, want a weak tag", tag)
+	}
+	// 附加字段不同，数据相同时仍返回 304
+	time.Sleep(time.Millisecond)
+	if w := send("req-2", "application/json", tag, 1); w.Code != http.StatusNotModified {
+		t.Fatalf("status = %d, ETag = %q, want %q", w.Code, w.Header().Get("ETag"), tag)
+	}
+	if w := send("req-3", "application/json", tag, 2); w.Code != http.StatusOK {
+		t.Fatalf("changed data status = %d", w.Code)
+	}
+	// 不同格式的 ETag 不同
+	if w := send("req-4", "application/yaml", tag, 1); w.Code != http.StatusOK || w.Header().Get("ETag") == tag {
+		t.Fatalf("yaml status = %d, ETag = %q", w.Code, w.Header().Get("ETag"))
+	}
+	// 字段名不同的响应体 ETag 不同
+	DefaultEnvelope = &Envelope{DataKey: "result", Extra: DefaultEnvelope.Extra}
+	if w := send("req-5", "application/json", tag, 1); w.Code != http.StatusOK || w.Header().Get("ETag") == tag {
+		t.Fatalf("renamed key status = %d, ETag = %q", w.Code, w.Header().Get("ETag"))
+	}
+}
+
+func TestOk_Version(t *testing.T) {
+	r := httptest.NewRequest("GET", "/items/1", nil)
+	r.Header.Set("If-None-Match", `"v1", "v2"`)
+	w := httptest.NewRecorder()
+	OkWithRequest(w, r, "item", WithVersion[string]("v2"), WithCacheControl[string](CacheControl{Private: true, MaxAge: time.Minute}), WithVary[string]("Authorization"))
+	if w.Code != http.StatusNotModified || w.Header().Get("ETag") != `"v2"` {
+		t.Fatalf("status = %d, ETag = %q", w.Code, w.Header().Get("ETag"))
+	}
+	if got := w.Header().Get("Cache-Control"); got != "private, max-age=60" {
+		t.Fatalf("Cache-Control = %q", got)
+	}
+	if got := w.Header().Values("Vary"); len(got) != 2 || got[0] != "Accept" || got[1] != "Authorization" {
+		t.Fatalf("Vary = %v", got)
+	}
+
+	w = httptest.NewRecorder()
+	OkWithRequest(w, r, "item", WithVersion[string]("v3"))
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"v3"` {
+		t.Fatalf("status = %d, ETag = %q", w.Code, w.Header().Get("ETag"))
+	}
+}
+
+func TestCacheHelpers(t *testing.T) {
+	c := CacheControl{Public: true, MaxAge: time.Hour, StaleWhileRevalidate: 30 * time.Second, Immutable: true}
+	if got := c.String(); got != "public, max-age=3600, stale-while-revalidate=30, immutable" {
+		t.Fatalf("CacheControl = %q", got)
+	}
+
+	w := httptest.NewRecorder()
+	w.Header().Set("Vary", "Accept, Origin")
+	AddVary(w, "accept", "Accept-Language", "Accept-Language")
+	if got := w.Header().Values("Vary"); len(got) != 2 || got[1] != "Accept-Language" {
+		t.Fatalf("Vary = %v", got)
+	}
+	SetCacheControl(w, CacheControl{})
+	if w.Header().Get("Cache-Control") != "" {
+		t.Fatal("zero CacheControl should not set header")
+	}
+}
@@ -0,0 +1,186 @@
+package resp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.yaml.in/yaml/v3"
+)
+
+/*
+消息目录使用示例:
+
+	// locales/zh-CN.yaml
+	// ok: 成功
+	// require phone: 请先绑定手机号
+	// "2001": 库存不足          # 未注册 MsgKey 的错误码以数字为 key，仅在 msg 为空或为默认文案时使用
+	// errors:
+	//   balance: 余额不足        # 嵌套 key 展开为 errors.balance
+	if err := resp.Messages.LoadDir("locales"); err != nil {
+		return err
+	}
+
+	// Accept-Language: zh-CN,zh;q=0.9,en;q=0.8
+	resp.Fail(w, resp.RequirePhoneErrMsg, resp.WithRequest[any](r), resp.WithCode[any](resp.RequirePhoneErrCode))
+	resp.Fail(w, "insufficient balance", resp.WithRequest[any](r), resp.WithMsgKey[any]("errors.balance"))
+*/
+
+// Catalog 多语言消息目录，key 为消息 key（未指定时为消息原文）
+type Catalog struct {
+	mu       sync.RWMutex
+	fallback string
+	messages map[string]map[string]string // lang -> key -> message
+}
+
+// NewCatalog fallback 为 Accept-Language 无匹配时使用的语言
+func NewCatalog(fallback string) *Catalog {
+	return &Catalog{fallback: strings.ToLower(fallback), messages: make(map[string]map[string]string)}
+}
+
+// Messages 响应使用的默认消息目录
+var Messages = NewCatalog("en")
+
+// Add 添加 lang 的消息，已存在的 key 会被覆盖
+func (c *Catalog) Add(lang string, messages map[string]string) {
+	lang = strings.ToLower(lang)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	m, ok := c.messages[lang]
+	if !ok {
+		m = make(map[string]string, len(messages))
+		c.messages[lang] = m
+	}
+	for k, v := range messages {
+		m[k] = v
+	}
+}
+
+// LoadFile 加载 <lang>.yaml / <lang>.yml / <lang>.json，嵌套对象的 key 以 "." 连接
+func (c *Catalog) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	var raw map[string]any
+	switch ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	default:
+		return fmt.Errorf("resp: unsupported catalog file %s", path)
+	}
+	if err != nil {
+		return fmt.Errorf("resp: parse catalog %s: %w", path, err)
+	}
+	messages := make(map[string]string)
+	flattenMessages("", raw, messages)
+	c.Add(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), messages)
+	return nil
+}
+
+// LoadDir 加载目录下所有 yaml / json 消息文件
+func (c *Catalog) LoadDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+			if err := c.LoadFile(filepath.Join(dir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func flattenMessages(prefix string, raw map[string]any, out map[string]string) {
+	for k, v := range raw {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch val := v.(type) {
+		case map[string]any:
+			flattenMessages(key, val, out)
+		case string:
+			out[key] = val
+		default:
+			out[key] = fmt.Sprint(val)
+		}
+	}
+}
+
+// Translate 按 Accept-Language 查找消息，返回消息与所用语言；
+// 依次尝试请求的语言（zh-CN 之后尝试 zh）与 fallback，同一语言内按 keys 的顺序查找
+func (c *Catalog) Translate(acceptLanguage string, keys ...string) (string, string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.messages) == 0 {
+		return "", "", false
+	}
+	for _, lang := range append(parseAcceptLanguage(acceptLanguage), c.fallback) {
+		for tag := lang; tag != ""; {
+			for _, key := range keys {
+				if msg, ok := c.messages[tag][key]; ok && key != "" {
+					return msg, tag, true
+				}
+			}
+			i := strings.LastIndex(tag, "-")
+			if i < 0 {
+				break
+			}
+			tag = tag[:i]
+		}
+	}
+	return "", "", false
+}
+
+// parseAcceptLanguage 按权重排序的语言标签（小写），忽略 * 与 q=0
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var langs []weighted
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if k != "q" {
+				continue
+			}
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		part = strings.TrimSpace(tag)
+		if part == "*" || q <= 0 {
+			continue
+		}
+		langs = append(langs, weighted{tag: strings.ToLower(strings.ReplaceAll(part, "_", "-")), q: q})
+	}
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].q > langs[j].q })
+	tags := make([]string, len(langs))
+	for i, l := range langs {
+		tags[i] = l.tag
+	}
+	return tags
+}
@@ -0,0 +1,189 @@
+package resp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lazyfury/bowlutils/openapi"
+)
+
+/*
+错误码使用示例:
+
+	// 在包级变量中声明，编号重复时 init 阶段 panic
+	var (
+		ErrBalance = resp.DeclareCode(2001, http.StatusConflict, "errors.balance", "账户余额不足")
+		ErrQuota   = resp.DeclareCode(2002, http.StatusTooManyRequests, "errors.quota", "超出调用配额")
+	)
+
+	// 错误码本身就是 error，FromError 会输出对应的状态码、错误码与（翻译后的）消息
+	if balance < amount {
+		resp.FromError(w, ErrBalance, resp.WithRequest[any](r))
+		return
+	}
+
+	// 生成错误码文档
+	os.WriteFile("docs/error-codes.md", []byte(resp.Codes.Markdown()), 0o644)
+
+	// 写入 OpenAPI：components.responses 中按状态码生成带示例的响应
+	resp.Codes.InjectOpenAPI(doc)
+	doc.AddPost("/orders", openapi.Operation{Responses: resp.Codes.Responses(ErrBalance, ErrQuota)})
+*/
+
+// ErrorCode 业务错误码，实现 error，可直接交给 FromError
+type ErrorCode struct {
+	Code        int    `json:"code"`
+	Status      int    `json:"status"`
+	MsgKey      string `json:"msg_key"`
+	Description string `json:"description"`
+}
+
+func (c ErrorCode) Error() string {
+	return fmt.Sprintf("%d: %s", c.Code, c.Description)
+}
+
+// StatusCode 对应的 HTTP 状态码
+func (c ErrorCode) StatusCode() int {
+	return c.Status
+}
+
+// CodeRegistry 错误码表
+type CodeRegistry struct {
+	mu    sync.RWMutex
+	codes map[int]ErrorCode
+}
+
+func NewCodeRegistry() *CodeRegistry {
+	return &CodeRegistry{codes: make(map[int]ErrorCode)}
+}
+
+// Codes 默认错误码表
+var Codes = NewCodeRegistry()
+
+func init() {
+	DeclareCode(BusinessErrCode, http.StatusBadRequest, BusinessErrMsg, "Generic business error")
+	DeclareCode(RequirePhoneErrCode, http.StatusBadRequest, RequirePhoneErrMsg, "Phone number binding required")
+	DeclareCode(InternalErrCode, http.StatusInternalServerError, InternalErrMsg, "Internal server error")
+	RegisterErrorAs(func(c ErrorCode) ErrorMapping {
+		return ErrorMapping{Status: c.Status, Code: c.Code, Msg: c.Description, MsgKey: c.MsgKey}
+	})
+}
+
+// Declare 声明错误码，编号已存在时 panic
+func (r *CodeRegistry) Declare(code ErrorCode) ErrorCode {
+	if code.Status == 0 {
+		code.Status = http.StatusBadRequest
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.codes[code.Code]; ok {
+		panic(fmt.Sprintf("resp: duplicate error code %d (%q and %q)", code.Code, existing.Description, code.Description))
+	}
+	r.codes[code.Code] = code
+	return code
+}
+
+// Lookup 查找错误码
+func (r *CodeRegistry) Lookup(code int) (ErrorCode, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.codes[code]
+	return c, ok
+}
+
+// List 按编号排序的全部错误码
+func (r *CodeRegistry) List() []ErrorCode {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list := make([]ErrorCode, 0, len(r.codes))
+	for _, c := range r.codes {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Code < list[j].Code })
+	return list
+}
+
+// DeclareCode 在默认错误码表中声明错误码
+func DeclareCode(code, status int, msgKey, description string) ErrorCode {
+	return Codes.Declare(ErrorCode{Code: code, Status: status, MsgKey: msgKey, Description: description})
+}
+
+// Markdown 生成错误码参考表
+func (r *CodeRegistry) Markdown() string {
+	var b strings.Builder
+	b.WriteString("| Code | HTTP Status | Message Key | Description |\n")
+	b.WriteString("|------|-------------|-------------|-------------|\n")
+	escape := strings.NewReplacer("|", `\|`, "\n", " ")
+	for _, c := range r.List() {
+		fmt.Fprintf(&b, "| %d | %d %s | %s | %s |\n", c.Code, c.Status, http.StatusText(c.Status), escape.Replace(c.MsgKey), escape.Replace(c.Description))
+	}
+	return b.String()
+}
+
+// JSON 生成错误码参考（按编号排序的数组）
+func (r *CodeRegistry) JSON() ([]byte, error) {
+	return json.MarshalIndent(r.List(), "", "  ")
+}
+
+// ErrorResponseSchema OpenAPI 中错误响应体的 schema 名称
+const ErrorResponseSchema = "ErrorResponse"
+
+func errorResponseName(status int) string {
+	return "Error" + strconv.Itoa(status)
+}
+
+// InjectOpenAPI 在 components.responses 中按 HTTP 状态码生成 Error<status> 响应，
+// 每个错误码作为一个示例
+func (r *CodeRegistry) InjectOpenAPI(doc *openapi.Document) {
+	doc.EnsureComponents()
+	envelope := DefaultEnvelope
+	doc.Components.Schemas[ErrorResponseSchema] = &openapi.Schema{
+		Type: "object",
+		Properties: map[string]*openapi.Schema{
+			orDefault(envelope.CodeKey, "code"): {Type: "integer"},
+			orDefault(envelope.MsgKey, "msg"):   {Type: "string"},
+			orDefault(envelope.DataKey, "data"): {},
+		},
+	}
+
+	byStatus := make(map[int][]ErrorCode)
+	for _, c := range r.List() {
+		byStatus[c.Status] = append(byStatus[c.Status], c)
+	}
+	for status, codes := range byStatus {
+		examples := make(map[string]openapi.Example, len(codes))
+		for _, c := range codes {
+			examples[strconv.Itoa(c.Code)] = openapi.Example{
+				Summary: c.Description,
+				Value: map[string]any{
+					orDefault(envelope.CodeKey, "code"): c.Code,
+					orDefault(envelope.MsgKey, "msg"):   c.Description,
+					orDefault(envelope.DataKey, "data"): nil,
+				},
+			}
+		}
+		doc.Components.Responses[errorResponseName(status)] = openapi.Response{
+			Description: http.StatusText(status),
+			Content: map[string]openapi.MediaType{
+				JSONContentType: {
+					Schema:   &openapi.Schema{Ref: "#/components/schemas/" + ErrorResponseSchema},
+					Examples: examples,
+				},
+			},
+		}
+	}
+}
+
+// Responses 返回引用 InjectOpenAPI 生成的响应，用于 Operation.Responses
+func (r *CodeRegistry) Responses(codes ...ErrorCode) openapi.Responses {
+	responses := make(openapi.Responses)
+	for _, c := range codes {
+		responses[strconv.Itoa(c.Status)] = openapi.Response{Ref: "#/components/responses/" + errorResponseName(c.Status)}
+	}
+	return responses
+}
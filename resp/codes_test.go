@@ -0,0 +1,57 @@
+package resp
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lazyfury/bowlutils/openapi"
+)
+
+func TestCodeRegistry(t *testing.T) {
+	codes := NewCodeRegistry()
+	balance := codes.Declare(ErrorCode{Code: 2001, Status: 409, MsgKey: "errors.balance", Description: "insufficient balance"})
+	codes.Declare(ErrorCode{Code: 2002, Status: 409, MsgKey: "errors.locked", Description: "account locked"})
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("duplicate code should panic")
+			}
+		}()
+		codes.Declare(ErrorCode{Code: 2001, Description: "again"})
+	}()
+
+	md := codes.Markdown()
+	if !strings.Contains(md, "| 2001 | 409 Conflict | errors.balance | insufficient balance |") {
+		t.Fatalf("markdown = %s", md)
+	}
+	data, err := codes.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list []ErrorCode
+	if err := json.Unmarshal(data, &list); err != nil || len(list) != 2 || list[0] != balance {
+		t.Fatalf("json = %s", data)
+	}
+
+	doc := openapi.NewDocument("3.0.3", openapi.NewInfo("test", "1.0"))
+	codes.InjectOpenAPI(doc)
+	resp, ok := doc.Components.Responses["Error409"]
+	if !ok || len(resp.Content[JSONContentType].Examples) != 2 {
+		t.Fatalf("responses = %+v", doc.Components.Responses)
+	}
+	if ref := codes.Responses(balance)["409"].Ref; ref != "#/components/responses/Error409" {
+		t.Fatalf("ref = %s", ref)
+	}
+
+	// 错误码可直接用于 FromError
+	w := httptest.NewRecorder()
+	FromError(w, balance)
+	var body map[string]any
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if w.Code != 409 || body["code"] != 2001.0 || body["msg"] != "insufficient balance" {
+		t.Fatalf("got %d %v", w.Code, body)
+	}
+}
@@ -0,0 +1,342 @@
+package resp
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"go.yaml.in/yaml/v3"
+)
+
+/*
+内容协商使用示例:
+
+	// 根据 Accept 选择 JSON / XML / YAML / MessagePack，无匹配时返回 406
+	resp.NewWithRequest(w, r, resp.WithData(orders)).Send()
+	resp.Ok(w, orders, resp.WithRequest[[]Order](r))
+
+	// 注册自定义格式
+	resp.RegisterEncoder("application/cbor", resp.EncoderFunc(func(w io.Writer, v any) error {
+		return cbor.NewEncoder(w).Encode(v)
+	}))
+*/
+
+// Encoder 响应体编码器
+type Encoder interface {
+	Encode(w io.Writer, v any) error
+}
+
+// EncoderFunc 函数形式的 Encoder
+type EncoderFunc func(w io.Writer, v any) error
+
+func (f EncoderFunc) Encode(w io.Writer, v any) error {
+	return f(w, v)
+}
+
+type encoderEntry struct {
+	mediaType string
+	encoder   Encoder
+}
+
+var (
+	encodersMu sync.RWMutex
+	// 按注册顺序排列，Accept 中多个格式权重相同时优先靠前的格式
+	encoders []encoderEntry
+)
+
+// JSONContentType 默认响应格式
+const JSONContentType = "application/json"
+
+var (
+	JSONEncoder = EncoderFunc(func(w io.Writer, v any) error {
+		return json.NewEncoder(w).Encode(v)
+	})
+	// XMLEncoder 与 YAMLEncoder 先经 JSON 转换，字段名与 JSON 一致（json tag、omitempty、MarshalJSON）
+	XMLEncoder = EncoderFunc(func(w io.Writer, v any) error {
+		v, err := jsonValue(v)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return err
+		}
+		return xml.NewEncoder(w).Encode(xmlValue{name: "response", value: v})
+	})
+	YAMLEncoder = EncoderFunc(func(w io.Writer, v any) error {
+		v, err := jsonValue(v)
+		if err != nil {
+			return err
+		}
+		enc := yaml.NewEncoder(w)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	})
+	MsgPackEncoder = EncoderFunc(func(w io.Writer, v any) error {
+		enc := msgpack.NewEncoder(w)
+		enc.SetCustomStructTag("json")
+		return enc.Encode(v)
+	})
+)
+
+func init() {
+	RegisterEncoder(JSONContentType, JSONEncoder)
+	RegisterEncoder("application/xml", XMLEncoder)
+	RegisterEncoder("text/xml", XMLEncoder)
+	RegisterEncoder("application/yaml", YAMLEncoder)
+	RegisterEncoder("application/x-yaml", YAMLEncoder)
+	RegisterEncoder("text/yaml", YAMLEncoder)
+	RegisterEncoder("application/msgpack", MsgPackEncoder)
+	RegisterEncoder("application/x-msgpack", MsgPackEncoder)
+	RegisterEncoder("application/vnd.msgpack", MsgPackEncoder)
+}
+
+// RegisterEncoder 注册 mediaType 的编码器，已注册时替换
+func RegisterEncoder(mediaType string, enc Encoder) {
+	mediaType = strings.ToLower(mediaType)
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	for i, e := range encoders {
+		if e.mediaType == mediaType {
+			encoders[i].encoder = enc
+			return
+		}
+	}
+	encoders = append(encoders, encoderEntry{mediaType: mediaType, encoder: enc})
+}
+
+// MediaTypes 已注册的格式
+func MediaTypes() []string {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	types := make([]string, len(encoders))
+	for i, e := range encoders {
+		types[i] = e.mediaType
+	}
+	return types
+}
+
+type acceptRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		typ, subtype, _ := strings.Cut(mediaType, "/")
+		if subtype == "" {
+			subtype = "*"
+		}
+		ranges = append(ranges, acceptRange{typ: typ, subtype: subtype, q: q})
+	}
+	return ranges
+}
+
+// quality 返回 Accept 中最具体的匹配范围给出的权重
+func quality(ranges []acceptRange, mediaType string) float64 {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.typ == typ && r.subtype == subtype:
+			s = 2
+		case r.typ == typ && r.subtype == "*":
+			s = 1
+		case r.typ == "*" && r.subtype == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
+
+// Negotiate 根据 Accept 选择编码器，Accept 为空时使用 JSON，无可接受格式时 ok 为 false
+// 权重相同（如 */*）时优先 JSON；浏览器的 Accept 以 text/html 为首选并附带 application/xml;q=0.9，
+// text/html 的权重不低于其他格式且 JSON 可接受时同样使用 JSON
+func Negotiate(accept string) (mediaType string, enc Encoder, ok bool) {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	if strings.TrimSpace(accept) == "" {
+		for _, e := range encoders {
+			if e.mediaType == JSONContentType {
+				return e.mediaType, e.encoder, true
+			}
+		}
+		return JSONContentType, JSONEncoder, true
+	}
+	ranges := parseAccept(accept)
+	best := 0.0
+	for _, e := range encoders {
+		q := quality(ranges, e.mediaType)
+		if q > best || q > 0 && q == best && e.mediaType == JSONContentType {
+			best, mediaType, enc = q, e.mediaType, e.encoder
+		}
+	}
+	if enc != nil && mediaType != JSONContentType && quality(ranges, "text/html") >= best {
+		for _, e := range encoders {
+			if e.mediaType == JSONContentType && quality(ranges, JSONContentType) > 0 {
+				return e.mediaType, e.encoder, true
+			}
+		}
+	}
+	return mediaType, enc, enc != nil
+}
+
+// write 按请求协商格式输出 body，没有请求时输出 JSON
+func (b *resp[T]) write(body any) {
+	mediaType, enc := JSONContentType, Encoder(JSONEncoder)
+	if b.r != nil {
+		var ok bool
+		mediaType, enc, ok = Negotiate(b.r.Header.Get("Accept"))
+		if !ok {
+			b.w.Header().Set("Content-Type", JSONContentType)
+			b.w.WriteHeader(http.StatusNotAcceptable)
+			_ = JSONEncoder.Encode(b.w, DefaultEnvelope.Wrap(b.context(), BusinessErrCode, "not acceptable", MediaTypes()))
+			return
+		}
+		AddVary(b.w, "Accept")
+	}
+	b.applyCache()
+	if b.etag != etagNone && b.status >= 200 && b.status < 300 {
+		b.writeCached(mediaType, enc, body)
+		return
+	}
+	b.w.Header().Set("Content-Type", mediaType)
+	b.w.WriteHeader(b.status)
+	if err := enc.Encode(b.w, body); err != nil {
+		http.Error(b.w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// jsonValue 将 v 按 JSON 编码后解码为 map[string]any / []any，整数保持为 int64
+func jsonValue(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var out any
+	if err := dec.Decode(&out); err != nil {
+		return nil, err
+	}
+	return convertNumbers(out), nil
+}
+
+func convertNumbers(v any) any {
+	switch x := v.(type) {
+	case map[string]any:
+		for k, e := range x {
+			x[k] = convertNumbers(e)
+		}
+	case []any:
+		for i, e := range x {
+			x[i] = convertNumbers(e)
+		}
+	case json.Number:
+		if n, err := x.Int64(); err == nil {
+			return n
+		}
+		f, _ := x.Float64()
+		return f
+	}
+	return v
+}
+
+// xmlValue 让 map 与 slice 也能编码为 XML，map 的 key 作为元素名；
+// key 不是合法的元素名时（如 "1"、"a b"、"x:y"）输出 <entry key="...">
+type xmlValue struct {
+	name  string
+	attr  []xml.Attr
+	value any
+}
+
+// xmlEntry map 中 key 对应的元素
+func xmlEntry(key string, value any) xmlValue {
+	if isXMLName(key) {
+		return xmlValue{name: key, value: value}
+	}
+	return xmlValue{name: "entry", attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: key}}, value: value}
+}
+
+// isXMLName key 能否直接作为元素名：字母或 _ 开头，由字母、数字、-、_、. 组成，
+// 不含 :（命名空间前缀），不以保留的 xml 开头
+func isXMLName(name string) bool {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_' || unicode.IsLetter(r):
+		case i > 0 && (r == '-' || r == '.' || unicode.IsDigit(r)):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (x xmlValue) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start = xml.StartElement{Name: xml.Name{Local: x.name}, Attr: x.attr}
+	if isNil(x.value) {
+		return e.EncodeElement("", start)
+	}
+	rv := reflect.ValueOf(x.value)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("resp: xml map key must be string, got %s", rv.Type().Key())
+		}
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, k := range keys {
+			if err := e.Encode(xmlEntry(k.String(), rv.MapIndex(k).Interface())); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		for i := 0; i < rv.Len(); i++ {
+			if err := e.Encode(xmlValue{name: "item", value: rv.Index(i).Interface()}); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	}
+	return e.EncodeElement(x.value, start)
+}
@@ -0,0 +1,130 @@
+package resp
+
+import (
+	"encoding/xml"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lazyfury/bowlutils/crud"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+		ok     bool
+	}{
+		{"", "application/json", true},
+		{"*/*", "application/json", true},
+		{"application/xml", "application/xml", true},
+		{"application/*", "application/json", true},
+		// 浏览器
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "application/json", true},
+		{"text/html, application/xml;q=0.9, */*;q=0.8", "application/json", true},
+		{"application/xml, text/html;q=0.5, */*;q=0.1", "application/xml", true},
+		{"text/html, application/xml;q=0.9", "application/xml", true},
+		{"application/json;q=0.5, application/yaml", "application/yaml", true},
+		{"application/*;q=0.2, application/msgpack", "application/msgpack", true},
+		{"*/*, application/json;q=0", "application/xml", true},
+		{"text/html", "", false},
+	}
+	for _, tt := range tests {
+		got, _, ok := Negotiate(tt.accept)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Negotiate(%q) = %q %v, want %q %v", tt.accept, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSend_ContentNegotiation(t *testing.T) {
+	type item struct {
+		Name string `json:"name" xml:"name"`
+	}
+	data := map[string]any{"items": []item{{Name: "a"}, {Name: "b"}}}
+
+	send := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		Ok(w, data, WithRequest[map[string]any](req))
+		return w
+	}
+
+	w := send("application/xml")
+	want := `<response><code>200</code><data><items><item><name>a</name></item><item><name>b</name></item></items></data><msg>ok</msg></response>`
+	if w.Header().Get("Content-Type") != "application/xml" || !strings.Contains(w.Body.String(), want) {
+		t.Fatalf("xml = %s", w.Body.String())
+	}
+
+	w = send("application/yaml")
+	if !strings.Contains(w.Body.String(), "msg: ok") {
+		t.Fatalf("yaml = %s", w.Body.String())
+	}
+
+	w = send("application/msgpack")
+	var decoded map[string]any
+	if err := msgpack.Unmarshal(w.Body.Bytes(), &decoded); err != nil || decoded["msg"] != "ok" {
+		t.Fatalf("msgpack = %v, %v", decoded, err)
+	}
+
+	w = send("text/html")
+	if w.Code != 406 {
+		t.Fatalf("status = %d", w.Code)
+	}
+}
+
+func TestXMLEncoder_Keys(t *testing.T) {
+	var buf strings.Builder
+	data := map[string]any{"1": "a", "a b": "b", "x:y": "c", "xmlns": "d", "ok_key-1.v": "e", "名称": "f", "<tag>": "g"}
+	if err := XMLEncoder.Encode(&buf, data); err != nil {
+		t.Fatal(err)
+	}
+	want := `<response><entry key="1">a</entry><entry key="&lt;tag&gt;">g</entry><entry key="a b">b</entry>` +
+		`<ok_key-1.v>e</ok_key-1.v><entry key="x:y">c</entry><entry key="xmlns">d</entry><名称>f</名称></response>`
+	if !strings.Contains(buf.String(), want) {
+		t.Fatalf("xml = %s", buf.String())
+	}
+	var out struct{}
+	if err := xml.Unmarshal([]byte(buf.String()), &out); err != nil {
+		t.Fatalf("malformed xml: %v", err)
+	}
+}
+
+func TestSend_FieldNames(t *testing.T) {
+	type item struct {
+		ID   uint   `json:"id"`
+		Note string `json:"note,omitempty"`
+	}
+	page := crud.Page[item]{PageNum: 2, PageSize: 10, Total: 11, Items: &[]item{{ID: 7}}}
+
+	send := func(accept string) string {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		Ok(w, page, WithRequest[crud.Page[item]](req))
+		return w.Body.String()
+	}
+
+	// 与 JSON 使用相同的字段名，omitempty 生效
+	xml := send("application/xml")
+	for _, want := range []string{"<page_num>2</page_num>", "<items><item><id>7</id></item></items>", "<total_exact>false</total_exact>"} {
+		if !strings.Contains(xml, want) {
+			t.Fatalf("xml missing %s: %s", want, xml)
+		}
+	}
+	if strings.Contains(xml, "PageNum") || strings.Contains(xml, "note") {
+		t.Fatalf("xml = %s", xml)
+	}
+
+	yaml := send("application/yaml")
+	for _, want := range []string{"page_num: 2", "total: 11", "- id: 7"} {
+		if !strings.Contains(yaml, want) {
+			t.Fatalf("yaml missing %q: %s", want, yaml)
+		}
+	}
+	if strings.Contains(yaml, "pagenum") || strings.Contains(yaml, "note") {
+		t.Fatalf("yaml = %s", yaml)
+	}
+}
@@ -0,0 +1,83 @@
+package resp
+
+import (
+	"context"
+	"time"
+
+	"github.com/lazyfury/bowlutils/logger"
+)
+
+/*
+Envelope 使用示例:
+
+	resp.DefaultEnvelope = &resp.Envelope{
+		CodeKey: "code",
+		MsgKey:  "message",
+		DataKey: "result",
+		Extra: map[string]resp.ExtraField{
+			"request_id": resp.RequestIDField,
+			"trace_id":   resp.TraceIDField,
+			"timestamp":  resp.TimestampField,
+		},
+	}
+
+	// 需要绑定请求才能取到 context 中的 request_id / trace_id
+	resp.NewWithRequest(w, r, resp.WithData(user)).Send()
+	// {"code":200,"message":"ok","result":{...},"request_id":"...","timestamp":1760000000}
+*/
+
+// ExtraField 从请求 context 计算附加字段，返回 nil 或空字符串时不输出
+type ExtraField func(ctx context.Context) any
+
+var (
+	// RequestIDField 取 logger.WithRequestID 放入的请求 ID
+	RequestIDField ExtraField = func(ctx context.Context) any { return logger.RequestID(ctx) }
+	// TraceIDField 取 logger.WithTraceID 放入的链路追踪 ID
+	TraceIDField ExtraField = func(ctx context.Context) any { return logger.TraceID(ctx) }
+	// TimestampField 当前 Unix 时间戳（秒）
+	TimestampField ExtraField = func(ctx context.Context) any { return time.Now().Unix() }
+)
+
+// Envelope 响应包装格式
+type Envelope struct {
+	CodeKey string
+	MsgKey  string
+	DataKey string
+	Extra   map[string]ExtraField
+}
+
+// DefaultEnvelope 全局包装格式，{code,msg,data}
+var DefaultEnvelope = &Envelope{CodeKey: "code", MsgKey: "msg", DataKey: "data"}
+
+// Wrap 构造响应体，ctx 为 nil 时使用 context.Background()
+func (e *Envelope) Wrap(ctx context.Context, code int, msg string, data any) map[string]any {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	body := make(map[string]any, len(e.Extra)+3)
+	for key, field := range e.Extra {
+		v := field(ctx)
+		if s, ok := v.(string); v == nil || (ok && s == "") {
+			continue
+		}
+		body[key] = v
+	}
+	body[orDefault(e.CodeKey, "code")] = code
+	body[orDefault(e.MsgKey, "msg")] = msg
+	body[orDefault(e.DataKey, "data")] = data
+	return body
+}
+
+// WithEnvelope 指定本次响应的包装格式
+func WithEnvelope[T any](e *Envelope) option[T] {
+	return func(r *resp[T]) {
+		r.envelope = e
+	}
+}
+
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,155 @@
+package resp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lazyfury/bowlutils/logger"
+)
+
+func TestEnvelope(t *testing.T) {
+	envelope := &Envelope{
+		CodeKey: "status",
+		MsgKey:  "message",
+		DataKey: "result",
+		Extra:   map[string]ExtraField{"request_id": RequestIDField, "trace_id": TraceIDField},
+	}
+	r := httptest.NewRequest("GET", "/", nil)
+	r = r.WithContext(logger.WithRequestID(r.Context(), "req-1"))
+	w := httptest.NewRecorder()
+	Ok(w, 1, WithRequest[int](r), WithEnvelope[int](envelope))
+
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["status"] != 200.0 || body["message"] != "ok" || body["result"] != 1.0 || body["request_id"] != "req-1" {
+		t.Fatalf("body = %v", body)
+	}
+	if _, ok := body["trace_id"]; ok {
+		t.Fatal("empty extra fields should be omitted")
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "zh.yaml"), []byte("require phone: 请先绑定手机号\nerrors:\n  balance: 余额不足\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "en.json"), []byte(`{"errors": {"balance": "insufficient balance"}}`), 0o644)
+
+	old := Messages
+	Messages = NewCatalog("en")
+	defer func() { Messages = old }()
+	if err := Messages.LoadDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		lang, key, want, wantLang string
+		ok                        bool
+	}{
+		{"zh-CN,zh;q=0.9,en;q=0.8", "require phone", "请先绑定手机号", "zh", true},
+		{"fr, en;q=0.5", "errors.balance", "insufficient balance", "en", true},
+		{"en;q=0.1, zh-TW", "errors.balance", "余额不足", "zh", true},
+		{"", "errors.balance", "insufficient balance", "en", true},
+		{"zh", "unknown", "", "", false},
+	}
+	for _, tt := range tests {
+		msg, lang, ok := Messages.Translate(tt.lang, tt.key)
+		if msg != tt.want || lang != tt.wantLang || ok != tt.ok {
+			t.Errorf("Translate(%q, %q) = %q %q %v", tt.lang, tt.key, msg, lang, ok)
+		}
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Language", "zh-CN")
+	w := httptest.NewRecorder()
+	Fail(w, RequirePhoneErrMsg, WithRequest[any](r), WithCode[any](RequirePhoneErrCode))
+	var body map[string]any
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if body["msg"] != "请先绑定手机号" || w.Header().Get("Content-Language") != "zh" {
+		t.Fatalf("body = %v, headers = %v", body, w.Header())
+	}
+}
+
+func TestTranslate_ByCode(t *testing.T) {
+	old, oldCodes := Messages, Codes
+	Messages, Codes = NewCatalog("en"), NewCodeRegistry()
+	defer func() { Messages, Codes = old, oldCodes }()
+	Messages.Add("zh", map[string]string{"errors.stock": "库存不足", "2902": "优惠券已过期", "coupon expired": "原文", "record not found": "记录不存在"})
+	Messages.Add("en", map[string]string{"2902": "Coupon expired"})
+	DeclareCode(2901, 409, "errors.stock", "Out of stock")
+
+	send := func(lang string, opts ...option[any]) (string, string) {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept-Language", lang)
+		w := httptest.NewRecorder()
+		New(w, append([]option[any]{WithRequest[any](r)}, opts...)...).Send()
+		var body map[string]any
+		json.Unmarshal(w.Body.Bytes(), &body)
+		msg, _ := body["msg"].(string)
+		return msg, w.Header().Get("Content-Language")
+	}
+
+	tests := []struct {
+		lang     string
+		opts     []option[any]
+		want     string
+		wantLang string
+	}{
+		// msg 为登记错误码的默认文案时使用 Codes 中登记的 MsgKey
+		{"zh", []option[any]{WithCode[any](2901), WithMsg[any]("Out of stock")}, "库存不足", "zh"},
+		// 具体的 msg 不被错误码的通用翻译覆盖
+		{"zh", []option[any]{WithCode[any](2901), WithMsg[any]("sold out: sku-1")}, "sold out: sku-1", ""},
+		// 未登记的错误码在 msg 为空时以数字为 key
+		{"zh", []option[any]{WithCode[any](2902), WithMsg[any]("")}, "优惠券已过期", "zh"},
+		// msg 原文优先于错误码
+		{"zh", []option[any]{WithCode[any](2902), WithMsg[any]("coupon expired")}, "原文", "zh"},
+		// 请求的语言优先于 key 的顺序
+		{"fr", []option[any]{WithCode[any](2902), WithMsg[any]("")}, "Coupon expired", "en"},
+		// 显式 MsgKey 不回退到错误码
+		{"zh", []option[any]{WithCode[any](2902), WithMsgKey[any]("errors.stock")}, "库存不足", "zh"},
+		{"zh", []option[any]{WithCode[any](2903), WithMsg[any]("record not found")}, "记录不存在", "zh"},
+	}
+	for _, tt := range tests {
+		if msg, lang := send(tt.lang, tt.opts...); msg != tt.want || lang != tt.wantLang {
+			t.Errorf("got %q %q, want %q %q", msg, lang, tt.want, tt.wantLang)
+		}
+	}
+}
+
+func TestTranslate_GenericCode(t *testing.T) {
+	old := Messages
+	Messages = NewCatalog("en")
+	defer func() { Messages = old }()
+	Messages.Add("zh", map[string]string{BusinessErrMsg: "业务错误", RequirePhoneErrMsg: "请先绑定手机号", "order not found": "订单不存在"})
+
+	tests := []struct {
+		send func(w *httptest.ResponseRecorder, r *http.Request)
+		want string
+	}{
+		{func(w *httptest.ResponseRecorder, r *http.Request) { Fail(w, RequirePhoneErrMsg, WithRequest[any](r)) }, "请先绑定手机号"},
+		{func(w *httptest.ResponseRecorder, r *http.Request) {
+			NotFound(w, "order not found", WithRequest[any](r))
+		}, "订单不存在"},
+		{func(w *httptest.ResponseRecorder, r *http.Request) {
+			Forbidden(w, "no access to order 7", WithRequest[any](r))
+		}, "no access to order 7"},
+		{func(w *httptest.ResponseRecorder, r *http.Request) { Fail(w, BusinessErrMsg, WithRequest[any](r)) }, "业务错误"},
+		{func(w *httptest.ResponseRecorder, r *http.Request) { Fail(w, "", WithRequest[any](r)) }, "业务错误"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept-Language", "zh")
+		w := httptest.NewRecorder()
+		tt.send(w, r)
+		var body map[string]any
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if body["code"] != float64(BusinessErrCode) || body["msg"] != tt.want {
+			t.Errorf("body = %v, want msg %q", body, tt.want)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package resp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/lazyfury/bowlutils/logger"
+	"gorm.io/gorm"
+)
+
+/*
+FromError 使用示例:
+
+	var ErrBalance = errors.New("insufficient balance")
+
+	func init() {
+		resp.RegisterError(ErrBalance, resp.ErrorMapping{Status: 409, Code: 2001, Msg: "余额不足"})
+		resp.RegisterErrorAs(func(e *QuotaError) resp.ErrorMapping {
+			return resp.ErrorMapping{Status: 429, Code: 2002, Msg: e.Error()}
+		})
+	}
+
+	user, err := repo.FindByID(id)
+	if err != nil {
+		resp.FromError(w, err) // gorm.ErrRecordNotFound -> 404
+		return
+	}
+*/
+
+var (
+	InternalErrCode = 500
+	InternalErrMsg  = "internal server error"
+)
+
+// ErrorMapping 错误对应的响应
+type ErrorMapping struct {
+	Status int
+	Code   int
+	Msg    string
+	// MsgKey 消息目录中的 key，为空时以 Msg 为 key，Msg 为错误码的默认文案时再尝试错误码
+	MsgKey string
+	// Data 响应中的 data，如字段错误详情
+	Data any
+}
+
+// FieldError 字段校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Tag     string `json:"tag"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// StatusCoder 自带 HTTP 状态码的错误（如 crud.ForbiddenError），未注册映射时使用其状态码，消息为状态码的通用描述
+type StatusCoder interface {
+	StatusCode() int
+}
+
+type errorMatcher func(err error) (ErrorMapping, bool)
+
+// ErrorRegistry 错误到响应的映射表，后注册的映射优先匹配
+type ErrorRegistry struct {
+	mu       sync.RWMutex
+	matchers []errorMatcher
+}
+
+func NewErrorRegistry() *ErrorRegistry {
+	return &ErrorRegistry{}
+}
+
+// Register 注册哨兵错误，使用 errors.Is 匹配
+func (r *ErrorRegistry) Register(target error, mapping ErrorMapping) {
+	r.RegisterFunc(func(err error) (ErrorMapping, bool) {
+		return mapping, errors.Is(err, target)
+	})
+}
+
+// RegisterFunc 注册自定义匹配函数
+func (r *ErrorRegistry) RegisterFunc(match func(err error) (ErrorMapping, bool)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.matchers = append(r.matchers, match)
+}
+
+// Resolve 查找 err 对应的映射
+func (r *ErrorRegistry) Resolve(err error) (ErrorMapping, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i := len(r.matchers) - 1; i >= 0; i-- {
+		if mapping, ok := r.matchers[i](err); ok {
+			return mapping, true
+		}
+	}
+	return ErrorMapping{}, false
+}
+
+// RegisterAs 注册错误类型，使用 errors.As 匹配
+func RegisterAs[E error](r *ErrorRegistry, fn func(e E) ErrorMapping) {
+	r.RegisterFunc(func(err error) (ErrorMapping, bool) {
+		var target E
+		if !errors.As(err, &target) {
+			return ErrorMapping{}, false
+		}
+		return fn(target), true
+	})
+}
+
+// Errors FromError 使用的默认映射表
+var Errors = NewErrorRegistry()
+
+func init() {
+	Errors.Register(gorm.ErrRecordNotFound, ErrorMapping{Status: http.StatusNotFound, Code: BusinessErrCode, Msg: "record not found"})
+	Errors.Register(context.DeadlineExceeded, ErrorMapping{Status: http.StatusGatewayTimeout, Code: BusinessErrCode, Msg: "request timeout"})
+	RegisterAs(Errors, func(e validator.ValidationErrors) ErrorMapping {
+		return ErrorMapping{Status: http.StatusUnprocessableEntity, Code: BusinessErrCode, Msg: "validation failed", Data: ValidationDetails(e)}
+	})
+}
+
+// RegisterError 向默认映射表注册哨兵错误
+func RegisterError(target error, mapping ErrorMapping) {
+	Errors.Register(target, mapping)
+}
+
+// RegisterErrorAs 向默认映射表注册错误类型
+func RegisterErrorAs[E error](fn func(e E) ErrorMapping) {
+	RegisterAs(Errors, fn)
+}
+
+// ValidationDetails 将校验错误转换为字段详情
+func ValidationDetails(errs validator.ValidationErrors) []FieldError {
+	details := make([]FieldError, 0, len(errs))
+	for _, fe := range errs {
+		msg := fmt.Sprintf("%s failed on the '%s' rule", fe.Field(), fe.Tag())
+		if fe.Param() != "" {
+			msg = fmt.Sprintf("%s failed on the '%s=%s' rule", fe.Field(), fe.Tag(), fe.Param())
+		}
+		details = append(details, FieldError{Field: fe.Field(), Tag: fe.Tag(), Param: fe.Param(), Message: msg})
+	}
+	return details
+}
+
+// ErrorResponse 解析 err 对应的响应：先查映射表，再使用 StatusCoder 的状态码与通用消息，
+// 其余错误记录日志（含堆栈）并返回不含内部信息的 500；err 为 nil 视为调用方错误，同样返回 500
+func ErrorResponse(err error) ErrorMapping {
+	if err == nil {
+		logger.Errorw("nil error passed to ErrorResponse", "stack", string(debug.Stack()))
+		return ErrorMapping{Status: http.StatusInternalServerError, Code: InternalErrCode, Msg: InternalErrMsg}
+	}
+	if mapping, ok := Errors.Resolve(err); ok {
+		return mapping
+	}
+	var sc StatusCoder
+	if errors.As(err, &sc) {
+		// err.Error() 可能包含表名等内部信息，只返回状态码对应的通用消息
+		msg := strings.ToLower(http.StatusText(sc.StatusCode()))
+		if msg == "" {
+			msg = BusinessErrMsg
+		}
+		return ErrorMapping{Status: sc.StatusCode(), Code: BusinessErrCode, Msg: msg}
+	}
+	logger.Errorw("unhandled error", "error", err.Error(), "stack", string(debug.Stack()))
+	return ErrorMapping{Status: http.StatusInternalServerError, Code: InternalErrCode, Msg: InternalErrMsg}
+}
+
+// FromError 按映射表将错误写为响应
+func FromError(w http.ResponseWriter, err error, opts ...option[any]) {
+	mapping := ErrorResponse(err)
+	opts = append([]option[any]{WithStatus[any](mapping.Status), WithCode[any](mapping.Code), WithMsg[any](mapping.Msg), WithMsgKey[any](mapping.MsgKey), WithData[any](mapping.Data)}, opts...)
+	New(w, opts...).Send()
+}
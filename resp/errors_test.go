@@ -0,0 +1,87 @@
+package resp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+type statusErr struct{}
+
+func (statusErr) Error() string   { return "forbidden: update on users" }
+func (statusErr) StatusCode() int { return 403 }
+
+func TestFromError(t *testing.T) {
+	// 测试结束后移除注册到默认映射表的映射
+	n := len(Errors.matchers)
+	t.Cleanup(func() {
+		Errors.mu.Lock()
+		Errors.matchers = Errors.matchers[:n]
+		Errors.mu.Unlock()
+	})
+	errCustom := errors.New("custom")
+	RegisterError(errCustom, ErrorMapping{Status: 409, Code: 2001, Msg: "conflict"})
+
+	type form struct {
+		Name string `validate:"required"`
+		Age  int    `validate:"min=18"`
+	}
+	validationErr := validator.New().Struct(form{Age: 3})
+
+	tests := []struct {
+		err    error
+		status int
+		code   int
+		msg    string
+	}{
+		{fmt.Errorf("find: %w", gorm.ErrRecordNotFound), 404, BusinessErrCode, "record not found"},
+		{context.DeadlineExceeded, 504, BusinessErrCode, "request timeout"},
+		{validationErr, 422, BusinessErrCode, "validation failed"},
+		{fmt.Errorf("wrap: %w", errCustom), 409, 2001, "conflict"},
+		// 不输出 Error() 中的内部信息
+		{statusErr{}, 403, BusinessErrCode, "forbidden"},
+		{errors.New("pq: connection refused"), 500, InternalErrCode, InternalErrMsg},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		FromError(w, tt.err)
+		var body struct {
+			Code int             `json:"code"`
+			Msg  string          `json:"msg"`
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if w.Code != tt.status || body.Code != tt.code || body.Msg != tt.msg {
+			t.Errorf("%v: got %d %d %q", tt.err, w.Code, body.Code, body.Msg)
+		}
+		if tt.status == 422 {
+			var fields []FieldError
+			if err := json.Unmarshal(body.Data, &fields); err != nil || len(fields) != 2 || fields[0].Field != "Name" || fields[1].Param != "18" {
+				t.Errorf("validation details = %s", body.Data)
+			}
+		}
+	}
+}
+
+func TestFromError_Nil(t *testing.T) {
+	w := httptest.NewRecorder()
+	FromError(w, nil)
+	var body struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != 500 || body.Code != InternalErrCode || body.Msg != InternalErrMsg {
+		t.Fatalf("got %d %+v", w.Code, body)
+	}
+}
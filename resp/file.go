@@ -0,0 +1,233 @@
+package resp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lazyfury/bowlutils/files"
+)
+
+/*
+文件下载使用示例:
+
+	// 直接挂载为 handler，支持 Range 续传与 304
+	mux.Handle("/files/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp.FromStorage(storage, r.PathValue("id")).ServeHTTP(w, r)
+	}))
+
+	// 或自行读取后输出
+	rc, meta, err := storage.Get(ctx, id)
+	if err != nil {
+		resp.FromError(w, err)
+		return
+	}
+	defer rc.Close()
+	resp.File(w, r, rc, meta, resp.Inline())
+*/
+
+// MetaETagKey Metadata.Extra 中的 ETag（如内容哈希），未设置时由 ID、大小与创建时间生成
+const MetaETagKey = "etag"
+
+type fileOptions struct {
+	inline       bool
+	cacheControl string
+}
+
+// FileOption File 选项
+type FileOption func(*fileOptions)
+
+// Inline 以 inline 方式输出（浏览器内预览），默认为 attachment
+func Inline() FileOption {
+	return func(o *fileOptions) {
+		o.inline = true
+	}
+}
+
+// WithFileCacheControl 设置 Cache-Control
+func WithFileCacheControl(v string) FileOption {
+	return func(o *fileOptions) {
+		o.cacheControl = v
+	}
+}
+
+func init() {
+	RegisterError(files.ErrNotFound, ErrorMapping{Status: http.StatusNotFound, Code: BusinessErrCode, Msg: "file not found"})
+}
+
+// FileETag 由元信息生成强 ETag
+func FileETag(meta files.Metadata) string {
+	if tag := meta.Extra[MetaETagKey]; tag != "" {
+		return quoteETag(tag, false)
+	}
+	return fmt.Sprintf(`"%s-%x-%x"`, meta.ID, meta.Size, meta.CreatedAt.UnixNano())
+}
+
+// File 输出文件内容：根据 meta 设置 Content-Type / Content-Disposition / ETag / Last-Modified，
+// 支持单个与多个 Range 以及 If-None-Match / If-Modified-Since / If-Range。
+// rc 不可 Seek 时需要 meta.Size（未知时忽略 Range），且只支持单个 Range，多个 Range 降级为 200 完整响应
+func File(w http.ResponseWriter, r *http.Request, rc io.Reader, meta files.Metadata, opts ...FileOption) {
+	var o fileOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	h := w.Header()
+	contentType := meta.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	h.Set("Content-Type", contentType)
+	h.Set("ETag", FileETag(meta))
+	if !meta.CreatedAt.IsZero() {
+		h.Set("Last-Modified", meta.CreatedAt.UTC().Format(http.TimeFormat))
+	}
+	disposition := "attachment"
+	if o.inline {
+		disposition = "inline"
+	}
+	if meta.Name != "" {
+		disposition = mime.FormatMediaType(disposition, map[string]string{"filename": meta.Name})
+	}
+	h.Set("Content-Disposition", disposition)
+	if o.cacheControl != "" {
+		h.Set("Cache-Control", o.cacheControl)
+	}
+
+	content, ok := rc.(io.ReadSeeker)
+	if !ok {
+		if meta.Size <= 0 {
+			// 大小未知时无法处理 Range，直接输出
+			if notModified(w, r, meta) {
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			if r.Method != http.MethodHead {
+				_, _ = io.Copy(w, rc)
+			}
+			return
+		}
+		if strings.Contains(r.Header.Get("Range"), ",") {
+			// 多个 Range 可能乱序或重叠，顺序读取的流无法回退，降级为 200 完整响应；
+			// 复制请求后再删除 Range，不修改调用方的请求
+			r = r.Clone(r.Context())
+			r.Header.Del("Range")
+		}
+		content = &forwardSeeker{r: rc, size: meta.Size}
+	}
+	http.ServeContent(w, r, "", meta.CreatedAt, content)
+}
+
+// FromStorage 从 storage 读取 id 并以 File 输出；条件请求命中时只调用 Stat
+func FromStorage(storage files.Storage, id string, opts ...FileOption) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != "" {
+			meta, err := storage.Stat(ctx, id)
+			if err != nil {
+				FromError(w, err, WithRequest[any](r))
+				return
+			}
+			w.Header().Set("ETag", FileETag(meta))
+			if notModified(w, r, meta) {
+				return
+			}
+		}
+		rc, meta, err := storage.Get(ctx, id)
+		if err != nil {
+			FromError(w, err, WithRequest[any](r))
+			return
+		}
+		defer rc.Close()
+		File(w, r, rc, meta, opts...)
+	})
+}
+
+// notModified 处理 GET/HEAD 的 If-None-Match / If-Modified-Since，命中时写入 304
+func notModified(w http.ResponseWriter, r *http.Request, meta files.Metadata) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if !etagMatch(inm, FileETag(meta)) {
+			return false
+		}
+	} else if ims := r.Header.Get("If-Modified-Since"); ims != "" && !meta.CreatedAt.IsZero() {
+		t, err := http.ParseTime(ims)
+		if err != nil || meta.CreatedAt.Truncate(time.Second).After(t) {
+			return false
+		}
+	} else {
+		return false
+	}
+	h := w.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	h.Del("Content-Disposition")
+	if !meta.CreatedAt.IsZero() {
+		h.Set("Last-Modified", meta.CreatedAt.UTC().Format(http.TimeFormat))
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagMatch If-None-Match 使用弱比较
+func etagMatch(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+var errSeekBackward = errors.New("resp: cannot seek backward in stream")
+
+// forwardSeeker 让只能顺序读取的流满足 http.ServeContent：
+// 探测大小的 Seek(0, End) 不移动读取位置，向前 Seek 在下次 Read 时丢弃中间数据
+type forwardSeeker struct {
+	r    io.Reader
+	size int64
+	pos  int64 // 逻辑位置
+	read int64 // 实际已读取的字节数
+}
+
+func (s *forwardSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.pos
+	case io.SeekEnd:
+		offset += s.size
+	default:
+		return 0, errors.New("resp: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("resp: negative position")
+	}
+	s.pos = offset
+	return offset, nil
+}
+
+func (s *forwardSeeker) Read(p []byte) (int, error) {
+	if s.pos < s.read {
+		return 0, errSeekBackward
+	}
+	if s.pos > s.read {
+		n, err := io.CopyN(io.Discard, s.r, s.pos-s.read)
+		s.read += n
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := s.r.Read(p)
+	s.read += int64(n)
+	s.pos = s.read
+	return n, err
+}
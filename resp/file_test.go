@@ -0,0 +1,89 @@
+package resp
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lazyfury/bowlutils/files"
+)
+
+func TestFromStorage(t *testing.T) {
+	storage, err := files.NewLocalStorage(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := storage.Save(context.Background(), strings.NewReader("0123456789"), files.Metadata{Name: "报告.txt", ContentType: "text/plain", Size: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := FromStorage(storage, id)
+
+	get := func(headers ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/files/"+id, nil)
+		for i := 0; i+1 < len(headers); i += 2 {
+			r.Header.Set(headers[i], headers[i+1])
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get()
+	if w.Code != 200 || w.Body.String() != "0123456789" || w.Header().Get("Content-Type") != "text/plain" {
+		t.Fatalf("got %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment; filename*=utf-8''") {
+		t.Fatalf("Content-Disposition = %s", cd)
+	}
+	etag := w.Header().Get("ETag")
+
+	w = get("Range", "bytes=2-4")
+	if w.Code != 206 || w.Body.String() != "234" || w.Header().Get("Content-Range") != "bytes 2-4/10" {
+		t.Fatalf("range: %d %q", w.Code, w.Body.String())
+	}
+
+	w = get("Range", "bytes=0-1,8-")
+	if w.Code != 206 || !strings.HasPrefix(w.Header().Get("Content-Type"), "multipart/byteranges") {
+		t.Fatalf("multi range: %d %v", w.Code, w.Header())
+	}
+
+	w = get("If-None-Match", etag)
+	if w.Code != 304 || w.Body.Len() != 0 {
+		t.Fatalf("if-none-match: %d", w.Code)
+	}
+	w = get("If-Modified-Since", w.Header().Get("Last-Modified"))
+	if w.Code != 304 {
+		t.Fatalf("if-modified-since: %d", w.Code)
+	}
+
+	missing := httptest.NewRecorder()
+	FromStorage(storage, "missing").ServeHTTP(missing, httptest.NewRequest("GET", "/", nil))
+	if missing.Code != 404 {
+		t.Fatalf("missing: %d", missing.Code)
+	}
+}
+
+func TestFile_Stream(t *testing.T) {
+	meta := files.Metadata{ID: "x", Size: 10}
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Range", "bytes=6-")
+	w := httptest.NewRecorder()
+	// 非 Seeker 的流
+	File(w, r, io.MultiReader(strings.NewReader("0123456789")), meta, Inline())
+	if w.Code != 206 || w.Body.String() != "6789" || w.Header().Get("Content-Disposition") != "inline" {
+		t.Fatalf("got %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+
+	r.Header.Set("Range", "bytes=0-1,4-5")
+	w = httptest.NewRecorder()
+	File(w, r, io.MultiReader(strings.NewReader("0123456789")), meta)
+	if w.Code != 200 || w.Body.String() != "0123456789" {
+		t.Fatalf("multi range on stream: %d %q", w.Code, w.Body.String())
+	}
+	if r.Header.Get("Range") != "bytes=0-1,4-5" {
+		t.Fatal("File must not modify the caller's request")
+	}
+}
@@ -0,0 +1,77 @@
+package resp
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/lazyfury/bowlutils/crud"
+)
+
+/*
+Page 使用示例:
+
+	var users []*User
+	page, err := repo.Page(&users, pageNum, pageSize, repo.QueryParamsToSearch(params)...)
+	if err != nil {
+		resp.FromError(w, err)
+		return
+	}
+	resp.Page(w, r, page)
+
+	// GET /users?name__like=a&page=2&page_size=10
+	// Link: </users?name__like=a&page=1&page_size=10>; rel="first", </users?name__like=a&page=1&page_size=10>; rel="prev", ...
+	// X-Total-Count: 42
+*/
+
+var (
+	// PageParam 页码查询参数名
+	PageParam = "page"
+	// PageSizeParam 每页条数查询参数名
+	PageSizeParam = "page_size"
+)
+
+// TotalCountHeader 总数响应头，仅在总数为精确值时输出
+const TotalCountHeader = "X-Total-Count"
+
+// Page 输出分页响应，并根据请求 URL（保留其他查询参数）设置 RFC 8288 Link 头；
+// 未统计总数的分页（WithoutTotal）只输出 first/prev/next，由 has_next 判断是否有下一页
+func Page[T any](w http.ResponseWriter, r *http.Request, page crud.Page[T], opts ...option[crud.Page[T]]) {
+	if links := pageLinks(r.URL, page); links != "" {
+		w.Header().Set("Link", links)
+	}
+	if page.TotalExact {
+		w.Header().Set(TotalCountHeader, strconv.FormatInt(page.Total, 10))
+	}
+	Ok(w, page, append([]option[crud.Page[T]]{WithRequest[crud.Page[T]](r)}, opts...)...)
+}
+
+func pageLinks[T any](u *url.URL, page crud.Page[T]) string {
+	if u == nil || page.PageSize <= 0 {
+		return ""
+	}
+	link := func(num int64, rel string) string {
+		q := u.Query()
+		q.Set(PageParam, strconv.FormatInt(num, 10))
+		q.Set(PageSizeParam, strconv.FormatInt(page.PageSize, 10))
+		ref := url.URL{Path: u.Path, RawPath: u.RawPath, RawQuery: q.Encode()}
+		return "<" + ref.String() + `>; rel="` + rel + `"`
+	}
+
+	num := page.PageNum
+	if num <= 0 {
+		num = 1
+	}
+	links := []string{link(1, "first")}
+	if num > 1 {
+		links = append(links, link(num-1, "prev"))
+	}
+	if page.HasNext {
+		links = append(links, link(num+1, "next"))
+	}
+	if page.TotalExact && page.PageCount > 0 {
+		links = append(links, link(page.PageCount, "last"))
+	}
+	return strings.Join(links, ", ")
+}
@@ -0,0 +1,38 @@
+package resp
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lazyfury/bowlutils/crud"
+)
+
+func TestPage(t *testing.T) {
+	items := []string{"a", "b"}
+	r := httptest.NewRequest("GET", "/users?name__like=a&page=2&page_size=2", nil)
+	w := httptest.NewRecorder()
+	Page(w, r, crud.Page[string]{PageNum: 2, PageSize: 2, PageCount: 3, Total: 6, TotalExact: true, HasNext: true, Items: &items})
+
+	want := `</users?name__like=a&page=1&page_size=2>; rel="first", ` +
+		`</users?name__like=a&page=1&page_size=2>; rel="prev", ` +
+		`</users?name__like=a&page=3&page_size=2>; rel="next", ` +
+		`</users?name__like=a&page=3&page_size=2>; rel="last"`
+	if got := w.Header().Get("Link"); got != want {
+		t.Fatalf("Link = %s", got)
+	}
+	if got := w.Header().Get(TotalCountHeader); got != "6" {
+		t.Fatalf("%s = %s", TotalCountHeader, got)
+	}
+
+	// 未统计总数
+	r = httptest.NewRequest("GET", "/users?page_size=2", nil)
+	w = httptest.NewRecorder()
+	Page(w, r, crud.Page[string]{PageNum: 1, PageSize: 2, HasNext: true, Items: &items})
+	want = `</users?page=1&page_size=2>; rel="first", </users?page=2&page_size=2>; rel="next"`
+	if got := w.Header().Get("Link"); got != want {
+		t.Fatalf("Link = %s", got)
+	}
+	if _, ok := w.Header()[TotalCountHeader]; ok {
+		t.Fatal("uncounted page should not set total header")
+	}
+}
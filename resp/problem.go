@@ -0,0 +1,163 @@
+package resp
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+)
+
+/*
+Problem Details（RFC 7807）使用示例:
+
+	// 全局
+	resp.DefaultMode = resp.ModeProblem
+
+	// 单个 handler
+	mux.Handle("/partner/orders", resp.ProblemDetails(ordersHandler))
+	// 或单次响应
+	resp.NotFound[any](w, "order not found", resp.WithMode[any](resp.ModeProblem), resp.WithInstance[any](r.URL.Path))
+
+	// 输出
+	// Content-Type: application/problem+json
+	// {"type":"about:blank","title":"Not Found","status":404,"detail":"order not found","instance":"/partner/orders/1","code":1000}
+*/
+
+// Mode 错误响应格式
+type Mode int
+
+const (
+	// ModeEnvelope {code,msg,data} 格式
+	ModeEnvelope Mode = iota
+	// ModeProblem application/problem+json 格式，仅用于 4xx/5xx 响应
+	ModeProblem
+)
+
+// DefaultMode 全局错误响应格式
+var DefaultMode = ModeEnvelope
+
+// ProblemContentType problem 响应的 Content-Type
+const ProblemContentType = "application/problem+json"
+
+// DefaultProblemType 未指定 type 时使用，title 为状态码对应的标准文本
+var DefaultProblemType = "about:blank"
+
+// Problem RFC 7807 Problem Details，Extensions 与标准字段平铺输出
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]any
+}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+	m := make(map[string]any, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	m["type"] = p.Type
+	m["title"] = p.Title
+	m["status"] = p.Status
+	if p.Detail != "" {
+		m["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		m["instance"] = p.Instance
+	}
+	return json.Marshal(m)
+}
+
+// WithMode 指定本次响应的错误格式
+func WithMode[T any](m Mode) option[T] {
+	return func(r *resp[T]) {
+		r.mode = m
+	}
+}
+
+// WithProblemType problem 的 type（URI）
+func WithProblemType[T any](uri string) option[T] {
+	return func(r *resp[T]) {
+		r.problemType = uri
+	}
+}
+
+// WithInstance problem 的 instance（URI）
+func WithInstance[T any](uri string) option[T] {
+	return func(r *resp[T]) {
+		r.instance = uri
+	}
+}
+
+// WithExtension problem 的扩展字段
+func WithExtension[T any](key string, value any) option[T] {
+	return func(r *resp[T]) {
+		if r.extensions == nil {
+			r.extensions = make(map[string]any)
+		}
+		r.extensions[key] = value
+	}
+}
+
+// problemWriter 标记由 ProblemDetails 包装的 handler
+type problemWriter struct {
+	http.ResponseWriter
+}
+
+func (w problemWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// isProblemWriter 沿 Unwrap 链查找 problemWriter，ProblemDetails 之后的中间件包装 writer 时仍使用 problem 格式
+func isProblemWriter(w http.ResponseWriter) bool {
+	for {
+		switch t := w.(type) {
+		case problemWriter:
+			return true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = t.Unwrap()
+		default:
+			return false
+		}
+	}
+}
+
+// ProblemDetails 中间件，handler 内的错误响应使用 problem 格式
+func ProblemDetails(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(problemWriter{w}, r)
+	})
+}
+
+// problem 由响应字段构造 Problem，code 与非空 data 作为扩展字段
+func (b *resp[T]) problem() Problem {
+	p := Problem{
+		Type:       b.problemType,
+		Title:      http.StatusText(b.status),
+		Status:     b.status,
+		Detail:     b.msg,
+		Instance:   b.instance,
+		Extensions: map[string]any{"code": b.code},
+	}
+	if p.Type == "" {
+		p.Type = DefaultProblemType
+	}
+	if data := any(b.data); !isNil(data) {
+		p.Extensions["data"] = data
+	}
+	for k, v := range b.extensions {
+		p.Extensions[k] = v
+	}
+	return p
+}
+
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface, reflect.Func, reflect.Chan:
+		return rv.IsNil()
+	}
+	return false
+}
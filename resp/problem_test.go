@@ -0,0 +1,82 @@
+package resp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProblemDetails(t *testing.T) {
+	h := ProblemDetails(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("ok") != "" {
+			Ok(w, "data")
+			return
+		}
+		NotFound[any](w, "order not found", WithInstance[any](r.URL.Path), WithExtension[any]("order_id", 7))
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/orders/7", nil))
+	if w.Code != 404 || w.Header().Get("Content-Type") != ProblemContentType {
+		t.Fatalf("got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"type": "about:blank", "title": "Not Found", "status": 404.0, "detail": "order not found",
+		"instance": "/orders/7", "code": float64(BusinessErrCode), "order_id": 7.0,
+	}
+	for k, v := range want {
+		if body[k] != v {
+			t.Errorf("%s = %v, want %v", k, body[k], v)
+		}
+	}
+	if _, ok := body["data"]; ok {
+		t.Error("nil data should be omitted")
+	}
+
+	// 成功响应保持 envelope 格式
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/orders?ok=1", nil))
+	if w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("success content type = %s", w.Header().Get("Content-Type"))
+	}
+
+	// 未包装的 handler 使用 DefaultMode
+	w = httptest.NewRecorder()
+	Fail[any](w, "bad")
+	if w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("default content type = %s", w.Header().Get("Content-Type"))
+	}
+}
+
+// statusRecorder 模拟 ProblemDetails 之后的日志中间件包装 writer
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func TestProblemDetails_WrappedWriter(t *testing.T) {
+	var rec *statusRecorder
+	h := ProblemDetails(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec = &statusRecorder{ResponseWriter: w}
+		NotFound[any](rec, "order not found")
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/orders/7", nil))
+	if w.Code != 404 || rec.status != 404 || w.Header().Get("Content-Type") != ProblemContentType {
+		t.Fatalf("got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+}
@@ -1,8 +1,10 @@
 package resp
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
 )
 
 var (
@@ -20,10 +22,24 @@ var (
 
 type resp[T any] struct {
 	w      http.ResponseWriter
+	r      *http.Request
 	status int
 	code   int
 	msg    string
+	msgKey string
 	data   T
+
+	envelope *Envelope
+
+	mode        Mode
+	problemType string
+	instance    string
+	extensions  map[string]any
+
+	etag         etagMode
+	version      string
+	cacheControl CacheControl
+	vary         []string
 }
 
 type option[T any] func(*resp[T])
@@ -52,8 +68,26 @@ func WithData[T any](d T) option[T] {
 	}
 }
 
+// WithMsgKey 消息目录中的 key，绑定请求时按 Accept-Language 翻译；
+// 未指定时以 msg 原文为 key，msg 为空或仍是错误码的默认文案时再尝试 Codes 中登记的 MsgKey 与错误码数字
+func WithMsgKey[T any](key string) option[T] {
+	return func(r *resp[T]) {
+		r.msgKey = key
+	}
+}
+
+// WithRequest 绑定请求，用于内容协商等需要请求信息的功能
+func WithRequest[T any](req *http.Request) option[T] {
+	return func(r *resp[T]) {
+		r.r = req
+	}
+}
+
 func New[T any](w http.ResponseWriter, opts ...option[T]) *resp[T] {
-	r := &resp[T]{w: w, status: 200, code: SuccessCode, msg: SuccessMsg}
+	r := &resp[T]{w: w, status: 200, code: SuccessCode, msg: SuccessMsg, mode: DefaultMode}
+	if isProblemWriter(w) {
+		r.mode = ModeProblem
+	}
 	for _, opt := range opts {
 		if opt == nil {
 			continue
@@ -63,15 +97,66 @@ func New[T any](w http.ResponseWriter, opts ...option[T]) *resp[T] {
 	return r
 }
 
+// NewWithRequest 绑定请求的响应，根据 Accept 选择输出格式
+func NewWithRequest[T any](w http.ResponseWriter, req *http.Request, opts ...option[T]) *resp[T] {
+	return New(w, append([]option[T]{WithRequest[T](req)}, opts...)...)
+}
+
 func (b *resp[T]) Send() {
-	b.w.Header().Set("Content-Type", "application/json")
+	if b.mode == ModeProblem && b.status >= 400 {
+		b.sendProblem()
+		return
+	}
+	b.translate()
+	b.write(b.currentEnvelope().Wrap(b.context(), b.code, b.msg, b.data))
+}
+
+// currentEnvelope 本次响应使用的包装格式
+func (b *resp[T]) currentEnvelope() *Envelope {
+	if b.envelope != nil {
+		return b.envelope
+	}
+	return DefaultEnvelope
+}
+
+func (b *resp[T]) context() context.Context {
+	if b.r != nil {
+		return b.r.Context()
+	}
+	return context.Background()
+}
+
+// translate 绑定请求时按 Accept-Language 从 Messages 翻译 msg；
+// 具体的 msg 不会被错误码的通用翻译覆盖
+func (b *resp[T]) translate() {
+	if b.r == nil {
+		return
+	}
+	keys := []string{b.msgKey}
+	if b.msgKey == "" {
+		keys = []string{b.msg}
+		c, declared := Codes.Lookup(b.code)
+		switch {
+		case declared && (b.msg == "" || b.msg == c.MsgKey || b.msg == c.Description):
+			keys = append(keys, c.MsgKey, strconv.Itoa(b.code))
+		case !declared && b.msg == "":
+			keys = append(keys, strconv.Itoa(b.code))
+		}
+	}
+	msg, lang, ok := Messages.Translate(b.r.Header.Get("Accept-Language"), keys...)
+	if !ok {
+		return
+	}
+	b.msg = msg
+	b.w.Header().Set("Content-Language", lang)
+	AddVary(b.w, "Accept-Language")
+}
+
+func (b *resp[T]) sendProblem() {
+	b.translate()
+	b.w.Header().Set("Content-Type", ProblemContentType)
 	b.w.WriteHeader(b.status)
-	err := json.NewEncoder(b.w).Encode(map[string]any{
-		"code": b.code,
-		"msg":  b.msg,
-		"data": b.data,
-	})
-	if err != nil {
+	if err := json.NewEncoder(b.w).Encode(b.problem()); err != nil {
 		http.Error(b.w, err.Error(), http.StatusInternalServerError)
 	}
 }
@@ -79,8 +164,9 @@ func (b *resp[T]) Send() {
 // ===== shortcuts =============================
 
 // Ok 成功响应
-func Ok[T any](w http.ResponseWriter, data T) {
-	New(w, WithData(data), WithMsg[T](SuccessMsg), WithCode[T](SuccessCode), WithStatus[T](200)).Send()
+func Ok[T any](w http.ResponseWriter, data T, opts ...option[T]) {
+	opts = append([]option[T]{WithData(data), WithMsg[T](SuccessMsg), WithCode[T](SuccessCode), WithStatus[T](200)}, opts...)
+	New(w, opts...).Send()
 }
 
 // Fail 失败响应
@@ -0,0 +1,334 @@
+package resp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+SSE 使用示例:
+
+	func progress(w http.ResponseWriter, r *http.Request) {
+		stream, err := resp.SSE(w, r, resp.WithHeartbeat(15*time.Second))
+		if err != nil {
+			resp.FromError(w, err)
+			return
+		}
+		defer stream.Close()
+
+		// 断线重连时从 Last-Event-ID 之后继续
+		since := stream.LastEventID()
+		_ = since
+
+		id, ch := bus.Subscribe("job.progress", 0)
+		defer bus.Unsubscribe("job.progress", id)
+		stream.Pipe(ch, func(v any) resp.Event {
+			p := v.(Progress)
+			return resp.Event{ID: p.Seq, Event: "progress", Data: p}
+		})
+	}
+
+NDJSON 导出示例:
+
+	stream, err := resp.NDJSON(w, r)
+	if err != nil {
+		resp.FromError(w, err)
+		return
+	}
+	resp.WriteSeq(stream, repo.Each(500))
+*/
+
+// DefaultHeartbeat SSE 心跳间隔，用于保持代理连接
+var DefaultHeartbeat = 15 * time.Second
+
+// ErrStreamClosed 流已关闭或客户端已断开
+var ErrStreamClosed = errors.New("resp: stream closed")
+
+// Event SSE 事件，Data 为 string / []byte 时原样输出，其他类型编码为 JSON
+type Event struct {
+	ID    string
+	Event string
+	Data  any
+	Retry time.Duration
+}
+
+// SSEStream Server-Sent Events 写入器
+type SSEStream struct {
+	w           http.ResponseWriter
+	rc          *http.ResponseController
+	ctx         context.Context
+	cancel      context.CancelFunc
+	mu          sync.Mutex
+	done        chan struct{} // 心跳 goroutine 退出后关闭
+	lastEventID string
+	heartbeat   time.Duration
+	retry       time.Duration
+}
+
+// SSEOption SSE 选项
+type SSEOption func(*SSEStream)
+
+// WithHeartbeat 心跳间隔，<= 0 时不发送心跳
+func WithHeartbeat(d time.Duration) SSEOption {
+	return func(s *SSEStream) {
+		s.heartbeat = d
+	}
+}
+
+// WithRetry 建立连接时告知客户端的重连间隔
+func WithRetry(d time.Duration) SSEOption {
+	return func(s *SSEStream) {
+		s.retry = d
+	}
+}
+
+// SSE 开始 text/event-stream 响应，客户端断开或 Close 后写入返回 ErrStreamClosed；
+// w 不支持 flush 时在写入响应头之前返回 http.ErrNotSupported
+func SSE(w http.ResponseWriter, r *http.Request, opts ...SSEOption) (*SSEStream, error) {
+	if !canFlush(w) {
+		return nil, http.ErrNotSupported
+	}
+	ctx, cancel := context.WithCancel(r.Context())
+	s := &SSEStream{
+		w:           w,
+		rc:          http.NewResponseController(w),
+		ctx:         ctx,
+		cancel:      cancel,
+		lastEventID: r.Header.Get("Last-Event-ID"),
+		heartbeat:   DefaultHeartbeat,
+		done:        make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if s.retry > 0 {
+		fmt.Fprintf(w, "retry: %d\n\n", s.retry.Milliseconds())
+	}
+	if err := s.rc.Flush(); err != nil {
+		cancel()
+		return nil, err
+	}
+	if s.heartbeat > 0 {
+		go s.heartbeatLoop()
+	} else {
+		close(s.done)
+	}
+	return s, nil
+}
+
+// LastEventID 客户端重连时携带的 Last-Event-ID
+func (s *SSEStream) LastEventID() string {
+	return s.lastEventID
+}
+
+// Done 客户端断开或 Close 后关闭
+func (s *SSEStream) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// Close 停止心跳并等待心跳 goroutine 退出，之后的写入返回 ErrStreamClosed；
+// handler 返回前必须调用，避免在 ResponseWriter 失效后继续写入
+func (s *SSEStream) Close() {
+	s.cancel()
+	<-s.done
+}
+
+// Send 写入一个事件并立即 flush
+func (s *SSEStream) Send(e Event) error {
+	data, err := eventData(e.Data)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	if e.ID != "" {
+		b.WriteString("id: " + singleLine(e.ID) + "\n")
+	}
+	if e.Event != "" {
+		b.WriteString("event: " + singleLine(e.Event) + "\n")
+	}
+	if e.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: " + strings.TrimSuffix(line, "\r") + "\n")
+	}
+	b.WriteString("\n")
+	return s.writeRaw(b.String())
+}
+
+// Pipe 将 ch 中的值作为事件发送，直到 ch 关闭或客户端断开；fn 为 nil 时值直接作为 Data
+func (s *SSEStream) Pipe(ch <-chan interface{}, fn func(v any) Event) error {
+	for {
+		select {
+		case <-s.ctx.Done():
+			return ErrStreamClosed
+		case v, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			e := Event{Data: v}
+			if fn != nil {
+				e = fn(v)
+			}
+			if err := s.Send(e); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *SSEStream) writeRaw(msg string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx.Err() != nil {
+		return ErrStreamClosed
+	}
+	if _, err := fmt.Fprint(s.w, msg); err != nil {
+		s.cancel()
+		return err
+	}
+	if err := s.rc.Flush(); err != nil {
+		s.cancel()
+		return err
+	}
+	return nil
+}
+
+func (s *SSEStream) heartbeatLoop() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.writeRaw(": ping\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func eventData(v any) (string, error) {
+	switch d := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return d, nil
+	case []byte:
+		return string(d), nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// canFlush 按 http.ResponseController 的方式（含 Unwrap 链）检查 w 是否支持 flush
+func canFlush(w http.ResponseWriter) bool {
+	for {
+		switch t := w.(type) {
+		case http.Flusher, interface{ FlushError() error }:
+			return true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = t.Unwrap()
+		default:
+			return false
+		}
+	}
+}
+
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// NDJSONContentType NDJSON 响应的 Content-Type
+const NDJSONContentType = "application/x-ndjson"
+
+// NDJSONStream 逐行输出 JSON（newline-delimited JSON），适用于大列表导出
+type NDJSONStream struct {
+	w   http.ResponseWriter
+	rc  *http.ResponseController
+	ctx context.Context
+	enc *json.Encoder
+}
+
+// NDJSON 开始 application/x-ndjson 响应，w 不支持 flush 时在写入响应头之前返回 http.ErrNotSupported
+func NDJSON(w http.ResponseWriter, r *http.Request) (*NDJSONStream, error) {
+	if !canFlush(w) {
+		return nil, http.ErrNotSupported
+	}
+	w.Header().Set("Content-Type", NDJSONContentType)
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	s := &NDJSONStream{w: w, rc: http.NewResponseController(w), ctx: r.Context(), enc: json.NewEncoder(w)}
+	if err := s.rc.Flush(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Write 写入一行，不立即 flush
+func (s *NDJSONStream) Write(v any) error {
+	if s.ctx.Err() != nil {
+		return ErrStreamClosed
+	}
+	return s.enc.Encode(v)
+}
+
+// Flush 将已写入的行发送给客户端
+func (s *NDJSONStream) Flush() error {
+	return s.rc.Flush()
+}
+
+// Pipe 将 ch 中的值逐行写入，直到 ch 关闭或客户端断开；ch 暂无数据时 flush
+func (s *NDJSONStream) Pipe(ch <-chan interface{}) error {
+	for {
+		select {
+		case <-s.ctx.Done():
+			return ErrStreamClosed
+		case v, ok := <-ch:
+			if !ok {
+				return s.Flush()
+			}
+			if err := s.Write(v); err != nil {
+				return err
+			}
+			if len(ch) == 0 {
+				if err := s.Flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// WriteSeq 写入迭代器中的所有值（如 crud.Repository.Each），遇到错误时停止
+func WriteSeq[T any](s *NDJSONStream, seq iter.Seq2[T, error]) error {
+	for v, err := range seq {
+		if err != nil {
+			return err
+		}
+		if err := s.Write(v); err != nil {
+			return err
+		}
+	}
+	return s.Flush()
+}
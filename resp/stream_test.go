@@ -0,0 +1,147 @@
+package resp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lazyfury/bowlutils/eventbus"
+)
+
+func TestSSE(t *testing.T) {
+	bus := eventbus.New()
+	id, ch := bus.Subscribe("progress", 0)
+	bus.Publish("progress", map[string]int{"done": 1})
+	bus.Publish("progress", "line1\nline2")
+	bus.Unsubscribe("progress", id)
+
+	r := httptest.NewRequest("GET", "/events", nil)
+	r.Header.Set("Last-Event-ID", "41")
+	w := httptest.NewRecorder()
+	stream, err := SSE(w, r, WithHeartbeat(0), WithRetry(3*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stream.LastEventID() != "41" {
+		t.Fatalf("LastEventID = %s", stream.LastEventID())
+	}
+	if err := stream.Send(Event{ID: "42", Event: "start", Data: "go"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Pipe(ch, nil); err != nil {
+		t.Fatal(err)
+	}
+	stream.Close()
+	if err := stream.Send(Event{Data: "late"}); err != ErrStreamClosed {
+		t.Fatalf("expected ErrStreamClosed, got %v", err)
+	}
+
+	want := "retry: 3000\n\n" +
+		"id: 42\nevent: start\ndata: go\n\n" +
+		"data: {\"done\":1}\n\n" +
+		"data: line1\ndata: line2\n\n"
+	if w.Body.String() != want {
+		t.Fatalf("body = %q", w.Body.String())
+	}
+	if w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("content type = %s", w.Header().Get("Content-Type"))
+	}
+}
+
+func TestSSE_Disconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := httptest.NewRequest("GET", "/events", nil).WithContext(ctx)
+	stream, err := SSE(httptest.NewRecorder(), r, WithHeartbeat(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	select {
+	case <-stream.Done():
+	case <-time.After(time.Second):
+		t.Fatal("stream should be done after client disconnect")
+	}
+	if err := stream.Pipe(make(chan interface{}), nil); err != ErrStreamClosed {
+		t.Fatalf("expected ErrStreamClosed, got %v", err)
+	}
+}
+
+func TestSSE_CloseStopsHeartbeat(t *testing.T) {
+	w := httptest.NewRecorder()
+	stream, err := SSE(w, httptest.NewRequest("GET", "/events", nil), WithHeartbeat(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	stream.Close()
+	stream.Close()
+
+	// Close 返回后心跳不再写入，读取 body 不与心跳竞争（-race 下验证）
+	body := w.Body.String()
+	if !strings.Contains(body, ": ping\n\n") {
+		t.Fatalf("body = %q", body)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if w.Body.String() != body {
+		t.Fatal("heartbeat written after Close")
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	stream, err := NDJSON(w, httptest.NewRequest("GET", "/export", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq := func(yield func(int, error) bool) {
+		for i := 1; i <= 3; i++ {
+			if !yield(i, nil) {
+				return
+			}
+		}
+	}
+	if err := WriteSeq(stream, seq); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.Body.String(); got != "1\n2\n3\n" || !strings.HasPrefix(w.Header().Get("Content-Type"), NDJSONContentType) {
+		t.Fatalf("body = %q", got)
+	}
+}
+
+// nonFlusher 隐藏 ResponseRecorder 的 Flush
+type nonFlusher struct {
+	http.ResponseWriter
+}
+
+func TestStream_NotFlushable(t *testing.T) {
+	starts := map[string]func(w http.ResponseWriter, r *http.Request) error{
+		"sse": func(w http.ResponseWriter, r *http.Request) error {
+			_, err := SSE(w, r)
+			return err
+		},
+		"ndjson": func(w http.ResponseWriter, r *http.Request) error {
+			_, err := NDJSON(w, r)
+			return err
+		},
+	}
+	for name, start := range starts {
+		rec := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		err := start(nonFlusher{rec}, r)
+		if !errors.Is(err, http.ErrNotSupported) {
+			t.Fatalf("%s: err = %v", name, err)
+		}
+		if rec.Header().Get("Content-Type") != "" || rec.Body.Len() != 0 {
+			t.Fatalf("%s: nothing should be written before the error, got %v %q", name, rec.Header(), rec.Body.String())
+		}
+
+		FromError(nonFlusher{rec}, err)
+		if rec.Code != http.StatusInternalServerError || rec.Header().Get("Content-Type") != JSONContentType {
+			t.Fatalf("%s: got %d %v", name, rec.Code, rec.Header())
+		}
+	}
+}
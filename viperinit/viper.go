@@ -1,6 +1,7 @@
 package viperinit
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/spf13/viper"
@@ -42,3 +43,15 @@ func NewViper(name, configType, path string, opts ...Option) *viper.Viper {
 	}
 	return v
 }
+
+// Section 将 key 对应的配置段解码为 T（使用 mapstructure tag，支持 "5s" 形式的 time.Duration）
+func Section[T any](v *viper.Viper, key string) (T, error) {
+	var out T
+	if !v.IsSet(key) {
+		return out, fmt.Errorf("viperinit: config section %q not found", key)
+	}
+	if err := v.UnmarshalKey(key, &out); err != nil {
+		return out, fmt.Errorf("viperinit: decode section %q: %w", key, err)
+	}
+	return out, nil
+}